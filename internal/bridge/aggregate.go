@@ -0,0 +1,460 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// NameSeparator joins a server name and a tool or prompt name in an
+// aggregated bridge, e.g. "github__create_issue". Resource URIs are
+// namespaced by prefixing the scheme instead: "github+file:///README.md".
+const NameSeparator = "__"
+
+// serverNamePattern keeps server names usable both as a name prefix and as a
+// URI scheme prefix
+var serverNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+
+// Aggregator presents several remote MCP servers to one stdio client as a
+// single MCP server. Each bridge's Name prefixes its tools and prompts and
+// namespaces its resource URIs, and every call is routed to the server that
+// owns the tool, prompt or resource.
+type Aggregator struct {
+	Bridges []*MCPBridge
+	Debug   bool
+	Logger  *slog.Logger // Default: text on the standard log output
+}
+
+// NewAggregator creates an aggregator over the given bridges, which must have
+// distinct names made of letters, digits and hyphens
+func NewAggregator(bridges []*MCPBridge, debug bool) (*Aggregator, error) {
+	seen := make(map[string]bool, len(bridges))
+	for _, b := range bridges {
+		if !serverNamePattern.MatchString(b.Name) {
+			return nil, fmt.Errorf("invalid server name %q: use letters, digits and hyphens", b.Name)
+		}
+		if seen[b.Name] {
+			return nil, fmt.Errorf("duplicate server name: %s", b.Name)
+		}
+		seen[b.Name] = true
+	}
+	return &Aggregator{Bridges: bridges, Debug: debug}, nil
+}
+
+// Log logs general messages at debug level
+func (a *Aggregator) Log(format string, v ...interface{}) {
+	a.logger().Debug(fmt.Sprintf(format, v...))
+}
+
+func (a *Aggregator) logger() *slog.Logger {
+	if a.Logger == nil {
+		return fallbackLogger(a.Debug)
+	}
+	return a.Logger
+}
+
+// redactor masks the secrets and redaction rules of every bridge, since
+// client messages may be meant for any of them
+func (a *Aggregator) redactor() (*redactor, error) {
+	var config Redaction
+	var secrets []string
+	for _, b := range a.Bridges {
+		config.Paths = append(config.Paths, b.Redact.Paths...)
+		config.Patterns = append(config.Patterns, b.Redact.Patterns...)
+		secrets = append(secrets, b.credentials()...)
+	}
+	return newRedactor(config, secrets)
+}
+
+// Run connects to every remote over HTTP POST and serves the merged server on
+// stdio until stdin is closed
+func (a *Aggregator) Run() error {
+	h, err := a.handler()
+	for _, b := range a.Bridges {
+		defer b.close()
+	}
+	if err != nil {
+		return err
+	}
+	redact, err := a.redactor()
+	if err != nil {
+		return err
+	}
+	a.Log("Aggregating %d remote MCP servers", len(a.Bridges))
+
+	// The client shares one stdin, so the strictest request limit applies
+	var maxRequest int64
+	for _, b := range a.Bridges {
+		if b.MaxRequestSize > 0 && (maxRequest == 0 || b.MaxRequestSize < maxRequest) {
+			maxRequest = b.MaxRequestSize
+		}
+	}
+	return serveStdio(context.Background(), os.Stdin, os.Stdout, h, a.logger(), redact, maxRequest)
+}
+
+func (a *Aggregator) handler() (*aggregator, error) {
+	agg := &aggregator{byName: make(map[string]*upstream), logf: a.Log}
+	for _, b := range a.Bridges {
+		h, err := b.postHandler()
+		if err != nil {
+			return nil, fmt.Errorf("server %s: %w", b.Name, err)
+		}
+		redact, err := b.redactor()
+		if err != nil {
+			return nil, fmt.Errorf("server %s: %w", b.Name, err)
+		}
+		up := &upstream{name: b.Name, handler: h, redact: redact}
+		agg.upstreams = append(agg.upstreams, up)
+		agg.byName[b.Name] = up
+	}
+	return agg, nil
+}
+
+// upstream is one named remote server behind the aggregator
+type upstream struct {
+	name    string
+	handler Handler
+	redact  *redactor // Masks the server's credentials in logged errors
+	ready   bool      // initialize succeeded
+}
+
+// aggregator is the Handler that fans requests out to upstreams and merges
+// their answers
+type aggregator struct {
+	upstreams []*upstream
+	byName    map[string]*upstream
+	logf      func(format string, v ...interface{})
+}
+
+// Handle implements Handler
+func (a *aggregator) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	switch req.Method {
+	case "initialize":
+		return a.initialize(ctx, req)
+	case "ping":
+		return newResult(req.ID, map[string]any{})
+	case "tools/list":
+		return a.list(ctx, req, "tools", "name", prefixName)
+	case "prompts/list":
+		return a.list(ctx, req, "prompts", "name", prefixName)
+	case "resources/list":
+		return a.list(ctx, req, "resources", "uri", prefixURI)
+	case "resources/templates/list":
+		return a.list(ctx, req, "resourceTemplates", "uriTemplate", prefixURI)
+	case "tools/call", "prompts/get":
+		return a.route(ctx, req, "name", splitName)
+	case "resources/read", "resources/subscribe", "resources/unsubscribe":
+		return a.route(ctx, req, "uri", splitURI)
+	case "completion/complete":
+		return a.complete(ctx, req)
+	case "logging/setLevel":
+		a.broadcast(ctx, req)
+		return newResult(req.ID, map[string]any{})
+	}
+
+	if req.ID == nil {
+		a.broadcast(ctx, req)
+		return nil, nil
+	}
+	return jsonrpc.NewError(req.ID, jsonrpc.MethodNotFound, "Method not found: "+req.Method, nil), nil
+}
+
+// initialize initializes every upstream and merges their capabilities and
+// instructions. Servers that fail to initialize are left out of the session.
+func (a *aggregator) initialize(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	results := a.fanOut(ctx, a.upstreams, req)
+
+	merged := map[string]any{
+		"capabilities": map[string]any{},
+		"serverInfo":   map[string]any{"name": "mcp-bridge", "version": "v1.0.0"},
+	}
+	var instructions []string
+	var firstErr *jsonrpc.Response
+	for i, up := range a.upstreams {
+		res := results[i]
+		if res.err != nil || res.resp.Error != nil {
+			a.logf("Server %s failed to initialize: %s", up.name, up.redact.String(describeFailure(res)))
+			if firstErr == nil && res.resp != nil {
+				firstErr = res.resp
+			}
+			continue
+		}
+		var result map[string]any
+		if err := json.Unmarshal(res.resp.Result, &result); err != nil {
+			a.logf("Server %s sent an invalid initialize result: %v", up.name, err)
+			continue
+		}
+		up.ready = true
+
+		if _, ok := merged["protocolVersion"]; !ok {
+			merged["protocolVersion"] = result["protocolVersion"]
+		}
+		if caps, ok := result["capabilities"].(map[string]any); ok {
+			mergeCapabilities(merged["capabilities"].(map[string]any), caps)
+		}
+		if text, ok := result["instructions"].(string); ok && text != "" {
+			instructions = append(instructions, fmt.Sprintf("[%s] %s", up.name, text))
+		}
+	}
+
+	if _, ok := merged["protocolVersion"]; !ok {
+		if firstErr != nil {
+			firstErr.ID = req.ID
+			return firstErr, nil
+		}
+		return nil, errors.New("no remote server could be initialized")
+	}
+	if len(instructions) > 0 {
+		merged["instructions"] = strings.Join(instructions, "\n\n")
+	}
+	return newResult(req.ID, merged)
+}
+
+// list collects every page of a list method from each ready upstream and
+// namespaces each item's key field
+func (a *aggregator) list(ctx context.Context, req *jsonrpc.Request, field, key string, rename func(server, value string) string) (*jsonrpc.Response, error) {
+	ready := a.ready()
+	all := make([][]any, len(ready))
+	var wg sync.WaitGroup
+	for i, up := range ready {
+		wg.Add(1)
+		go func(i int, up *upstream) {
+			defer wg.Done()
+			items, err := listAll(ctx, up.handler, req, field)
+			if err != nil {
+				a.logf("Server %s failed %s: %s", up.name, req.Method, up.redact.String(err.Error()))
+				return
+			}
+			for _, item := range items {
+				if m, ok := item.(map[string]any); ok {
+					if value, ok := m[key].(string); ok {
+						m[key] = rename(up.name, value)
+					}
+				}
+			}
+			all[i] = items
+		}(i, up)
+	}
+	wg.Wait()
+
+	merged := []any{}
+	for _, items := range all {
+		merged = append(merged, items...)
+	}
+	return newResult(req.ID, map[string]any{field: merged})
+}
+
+// listAll follows nextCursor until the upstream has returned every item
+func listAll(ctx context.Context, h Handler, req *jsonrpc.Request, field string) ([]any, error) {
+	var items []any
+	params := map[string]any{}
+	for {
+		resp, err := h.Handle(ctx, withParams(req, params))
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			return nil, errors.New("no response")
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s (code %d)", resp.Error.Message, resp.Error.Code)
+		}
+		var page map[string]any
+		if err := json.Unmarshal(resp.Result, &page); err != nil {
+			return nil, fmt.Errorf("invalid result: %w", err)
+		}
+		if pageItems, ok := page[field].([]any); ok {
+			items = append(items, pageItems...)
+		}
+		cursor, _ := page["nextCursor"].(string)
+		if cursor == "" {
+			return items, nil
+		}
+		params["cursor"] = cursor
+	}
+}
+
+// route forwards a request to the upstream named by its namespaced key param
+// and namespaces any resource URIs in the result
+func (a *aggregator) route(ctx context.Context, req *jsonrpc.Request, key string, split func(string) (string, string, bool)) (*jsonrpc.Response, error) {
+	params, err := decodeParams(req)
+	if err != nil {
+		return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, err.Error(), nil), nil
+	}
+	value, _ := params[key].(string)
+	server, original, ok := split(value)
+	up := a.byName[server]
+	if !ok || up == nil || !up.ready {
+		return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, fmt.Sprintf("Unknown %s: %s", key, value), nil), nil
+	}
+
+	params[key] = original
+	resp, err := up.handler.Handle(ctx, withParams(req, params))
+	if err != nil || resp == nil || resp.Error != nil {
+		return resp, err
+	}
+	resp.Result = namespaceResultURIs(up.name, resp.Result)
+	return resp, nil
+}
+
+// complete routes completion/complete by the prompt or resource it refers to
+func (a *aggregator) complete(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	params, err := decodeParams(req)
+	if err != nil {
+		return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, err.Error(), nil), nil
+	}
+	ref, _ := params["ref"].(map[string]any)
+	key, split := "name", splitName
+	if ref["type"] == "ref/resource" {
+		key, split = "uri", splitURI
+	}
+	value, _ := ref[key].(string)
+	server, original, ok := split(value)
+	up := a.byName[server]
+	if !ok || up == nil || !up.ready {
+		return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, fmt.Sprintf("Unknown reference: %s", value), nil), nil
+	}
+	ref[key] = original
+	return up.handler.Handle(ctx, withParams(req, params))
+}
+
+// broadcast sends a request or notification to every ready upstream,
+// ignoring the answers
+func (a *aggregator) broadcast(ctx context.Context, req *jsonrpc.Request) {
+	ready := a.ready()
+	for i, res := range a.fanOut(ctx, ready, req) {
+		if res.err != nil {
+			a.logf("Server %s failed %s: %s", ready[i].name, req.Method, ready[i].redact.String(res.err.Error()))
+		}
+	}
+}
+
+func (a *aggregator) ready() []*upstream {
+	var ready []*upstream
+	for _, up := range a.upstreams {
+		if up.ready {
+			ready = append(ready, up)
+		}
+	}
+	return ready
+}
+
+type fanOutResult struct {
+	resp *jsonrpc.Response
+	err  error
+}
+
+// fanOut sends the same request to several upstreams concurrently
+func (a *aggregator) fanOut(ctx context.Context, ups []*upstream, req *jsonrpc.Request) []fanOutResult {
+	results := make([]fanOutResult, len(ups))
+	var wg sync.WaitGroup
+	for i, up := range ups {
+		wg.Add(1)
+		go func(i int, up *upstream) {
+			defer wg.Done()
+			resp, err := up.handler.Handle(ctx, req)
+			if err == nil && resp == nil && req.ID != nil {
+				err = errors.New("no response")
+			}
+			results[i] = fanOutResult{resp, err}
+		}(i, up)
+	}
+	wg.Wait()
+	return results
+}
+
+func describeFailure(res fanOutResult) string {
+	if res.err != nil {
+		return res.err.Error()
+	}
+	return res.resp.Error.Message
+}
+
+// mergeCapabilities unions capability objects, OR-ing boolean flags such as
+// listChanged and subscribe
+func mergeCapabilities(dst, src map[string]any) {
+	for name, value := range src {
+		existing, ok := dst[name].(map[string]any)
+		incoming, isMap := value.(map[string]any)
+		if !ok || !isMap {
+			if _, present := dst[name]; !present {
+				dst[name] = value
+			}
+			continue
+		}
+		for flag, v := range incoming {
+			if b, ok := v.(bool); ok {
+				prev, _ := existing[flag].(bool)
+				existing[flag] = prev || b
+			} else if _, present := existing[flag]; !present {
+				existing[flag] = v
+			}
+		}
+	}
+}
+
+func prefixName(server, name string) string {
+	return server + NameSeparator + name
+}
+
+func splitName(name string) (string, string, bool) {
+	return strings.Cut(name, NameSeparator)
+}
+
+func prefixURI(server, uri string) string {
+	return server + "+" + uri
+}
+
+func splitURI(uri string) (string, string, bool) {
+	return strings.Cut(uri, "+")
+}
+
+// namespaceResultURIs rewrites resource URIs in tool results and resource
+// contents so the client can read them back through the aggregator
+func namespaceResultURIs(server string, raw json.RawMessage) json.RawMessage {
+	var result map[string]any
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return raw
+	}
+
+	changed := false
+	rewrite := func(m map[string]any) {
+		if uri, ok := m["uri"].(string); ok {
+			m["uri"] = prefixURI(server, uri)
+			changed = true
+		}
+	}
+	for _, field := range []string{"content", "contents"} {
+		items, _ := result[field].([]any)
+		for _, item := range items {
+			m, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			switch {
+			case field == "contents", m["type"] == "resource_link":
+				rewrite(m)
+			case m["type"] == "resource":
+				if res, ok := m["resource"].(map[string]any); ok {
+					rewrite(res)
+				}
+			}
+		}
+	}
+
+	if !changed {
+		return raw
+	}
+	if data, err := json.Marshal(result); err == nil {
+		return data
+	}
+	return raw
+}
@@ -0,0 +1,273 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// fakeMCPServer is a minimal remote MCP server answering JSON-RPC over POST
+type fakeMCPServer struct {
+	name      string
+	tools     []map[string]any
+	resources []map[string]any
+	pageSize  int
+	calls     []string // "method name-or-uri" for every request received
+}
+
+func (s *fakeMCPServer) Handle(_ context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	params, _ := decodeParams(req)
+	target, _ := params["name"].(string)
+	if uri, ok := params["uri"].(string); ok {
+		target = uri
+	}
+	s.calls = append(s.calls, strings.TrimSpace(req.Method+" "+target))
+
+	switch req.Method {
+	case "initialize":
+		return newResult(req.ID, map[string]any{
+			"protocolVersion": "2025-06-18",
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": s.name == "beta"}},
+			"serverInfo":      map[string]any{"name": s.name},
+			"instructions":    "Use " + s.name + " tools.",
+		})
+	case "tools/list":
+		start := 0
+		if cursor, ok := params["cursor"].(string); ok {
+			fmt.Sscan(cursor, &start)
+		}
+		end := len(s.tools)
+		result := map[string]any{}
+		if s.pageSize > 0 && start+s.pageSize < end {
+			end = start + s.pageSize
+			result["nextCursor"] = fmt.Sprint(end)
+		}
+		result["tools"] = s.tools[start:end]
+		return newResult(req.ID, result)
+	case "tools/call":
+		args, _ := params["arguments"].(map[string]any)
+		if target == "fail" {
+			return newResult(req.ID, map[string]any{
+				"isError": true,
+				"content": []any{map[string]any{"type": "text", "text": "tool failed"}},
+			})
+		}
+		return newResult(req.ID, map[string]any{
+			"content": []any{
+				map[string]any{"type": "text", "text": fmt.Sprintf("%s ran %s with %v", s.name, target, args)},
+				map[string]any{"type": "resource_link", "uri": "file:///out.txt", "name": "out"},
+			},
+		})
+	case "resources/list":
+		return newResult(req.ID, map[string]any{"resources": s.resources})
+	case "resources/read":
+		return newResult(req.ID, map[string]any{
+			"contents": []any{map[string]any{"uri": target, "text": s.name + " contents"}},
+		})
+	}
+	if req.ID == nil {
+		return nil, nil
+	}
+	return jsonrpc.NewError(req.ID, jsonrpc.MethodNotFound, "Method not found", nil), nil
+}
+
+// ServeHTTP exposes the fake server over HTTP POST
+func (s *fakeMCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	msg, err := jsonrpc.Parse(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, _ := s.Handle(r.Context(), msg.(*jsonrpc.Request))
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// runStdio feeds newline-delimited requests through h and returns the
+// decoded responses
+func runStdio(t *testing.T, h Handler, requests ...string) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(requests, "\n") + "\n")
+	if err := serveStdio(context.Background(), in, &out, h, nil, nil, 0); err != nil {
+		t.Fatalf("serveStdio failed: %v", err)
+	}
+
+	var responses []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp map[string]any
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("Invalid response on stdout: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestAggregator(t *testing.T) {
+	alpha := &fakeMCPServer{
+		name:     "alpha",
+		pageSize: 1,
+		tools: []map[string]any{
+			{"name": "search", "inputSchema": map[string]any{"type": "object"}},
+			{"name": "fail", "inputSchema": map[string]any{"type": "object"}},
+		},
+		resources: []map[string]any{{"uri": "file:///a.txt", "name": "a"}},
+	}
+	beta := &fakeMCPServer{
+		name:  "beta",
+		tools: []map[string]any{{"name": "search", "inputSchema": map[string]any{"type": "object"}}},
+	}
+	alphaSrv := httptest.NewServer(alpha)
+	defer alphaSrv.Close()
+	betaSrv := httptest.NewServer(beta)
+	defer betaSrv.Close()
+
+	alphaBridge := New(alphaSrv.URL, "", false)
+	alphaBridge.Name = "alpha"
+	betaBridge := New(betaSrv.URL, "", false)
+	betaBridge.Name = "beta"
+	agg, err := NewAggregator([]*MCPBridge{alphaBridge, betaBridge}, false)
+	if err != nil {
+		t.Fatalf("NewAggregator failed: %v", err)
+	}
+	h, err := agg.handler()
+	if err != nil {
+		t.Fatalf("Failed to build handler: %v", err)
+	}
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"beta__search","arguments":{"q":"go"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"resources/read","params":{"uri":"alpha+file:///a.txt"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"gamma__search"}}`,
+	)
+	if len(responses) != 6 {
+		t.Fatalf("Expected 6 responses (notification has none), got %d: %v", len(responses), responses)
+	}
+
+	t.Run("initialize merges servers", func(t *testing.T) {
+		result := responses[0]["result"].(map[string]any)
+		caps := result["capabilities"].(map[string]any)["tools"].(map[string]any)
+		if caps["listChanged"] != true {
+			t.Errorf("Expected listChanged to be OR-ed across servers, got %v", caps)
+		}
+		if got := result["instructions"]; got != "[alpha] Use alpha tools.\n\n[beta] Use beta tools." {
+			t.Errorf("Unexpected merged instructions: %q", got)
+		}
+		if name := result["serverInfo"].(map[string]any)["name"]; name != "mcp-bridge" {
+			t.Errorf("Expected bridge server info, got %v", name)
+		}
+	})
+
+	t.Run("tools are prefixed across pages", func(t *testing.T) {
+		var names []string
+		for _, tool := range responses[1]["result"].(map[string]any)["tools"].([]any) {
+			names = append(names, tool.(map[string]any)["name"].(string))
+		}
+		if got := strings.Join(names, ","); got != "alpha__search,alpha__fail,beta__search" {
+			t.Errorf("Unexpected tool names: %s", got)
+		}
+	})
+
+	t.Run("calls route to the owning server", func(t *testing.T) {
+		content := responses[2]["result"].(map[string]any)["content"].([]any)
+		if text := content[0].(map[string]any)["text"]; text != "beta ran search with map[q:go]" {
+			t.Errorf("Unexpected tool result: %v", text)
+		}
+		if uri := content[1].(map[string]any)["uri"]; uri != "beta+file:///out.txt" {
+			t.Errorf("Expected resource link to be namespaced, got %v", uri)
+		}
+	})
+
+	t.Run("resources are namespaced", func(t *testing.T) {
+		resources := responses[3]["result"].(map[string]any)["resources"].([]any)
+		if uri := resources[0].(map[string]any)["uri"]; uri != "alpha+file:///a.txt" {
+			t.Errorf("Unexpected resource URI: %v", uri)
+		}
+		contents := responses[4]["result"].(map[string]any)["contents"].([]any)
+		if uri := contents[0].(map[string]any)["uri"]; uri != "alpha+file:///a.txt" {
+			t.Errorf("Unexpected read URI: %v", uri)
+		}
+		if last := alpha.calls[len(alpha.calls)-1]; last != "resources/read file:///a.txt" {
+			t.Errorf("Expected original URI upstream, got %q", last)
+		}
+	})
+
+	t.Run("unknown server", func(t *testing.T) {
+		errObj, ok := responses[5]["error"].(map[string]any)
+		if !ok || errObj["code"] != float64(jsonrpc.InvalidParams) {
+			t.Errorf("Expected InvalidParams for unknown server, got %v", responses[5])
+		}
+	})
+
+	t.Run("notifications are broadcast", func(t *testing.T) {
+		for _, s := range []*fakeMCPServer{alpha, beta} {
+			if s.calls[1] != "notifications/initialized" {
+				t.Errorf("Expected %s to receive notifications/initialized, got %v", s.name, s.calls)
+			}
+		}
+	})
+}
+
+func TestNewAggregatorValidatesNames(t *testing.T) {
+	for _, names := range [][]string{{"", "b"}, {"a__b", "c"}, {"same", "same"}, {"1st", "b"}} {
+		var bridges []*MCPBridge
+		for _, name := range names {
+			b := New("http://localhost", "", false)
+			b.Name = name
+			bridges = append(bridges, b)
+		}
+		if _, err := NewAggregator(bridges, false); err == nil {
+			t.Errorf("Expected error for names %v", names)
+		}
+	}
+}
+
+func TestAggregatorRedactsServerErrors(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	live := httptest.NewServer(&fakeMCPServer{name: "live"})
+	defer live.Close()
+
+	down := New(dead.URL+"/mcp?token=supersecret", "", false)
+	down.Name = "down"
+	up := New(live.URL, "", false)
+	up.Name = "up"
+	a, err := NewAggregator([]*MCPBridge{down, up}, true)
+	if err != nil {
+		t.Fatalf("NewAggregator failed: %v", err)
+	}
+	var logs bytes.Buffer
+	a.Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	h, err := a.handler()
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+
+	runStdio(t, h, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	if !strings.Contains(logs.String(), "Server down failed to initialize") {
+		t.Fatalf("Expected the failure to be logged, got %s", logs.String())
+	}
+	if strings.Contains(logs.String(), "supersecret") {
+		t.Errorf("Expected the URL token to be redacted, got %s", logs.String())
+	}
+}
@@ -0,0 +1,326 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// defaultApprovalTimeout bounds how long a call waits for a decision
+const defaultApprovalTimeout = 2 * time.Minute
+
+// Approval sends selected tool calls to an external hook before forwarding
+// them. The hook is either a local command, which receives the call as JSON
+// on stdin and answers on stdout, or an HTTP endpoint that receives it as a
+// POST body. Calls are denied when the hook fails or times out.
+type Approval struct {
+	Tools       []string      // Glob patterns of tools that need approval
+	NotReadOnly bool          // Also tools not annotated with readOnlyHint
+	Command     []string      // Hook command and arguments
+	URL         string        // Hook endpoint, used when Command is empty
+	Timeout     time.Duration // Default 2m
+}
+
+// IsZero reports whether no approval hook is configured
+func (a Approval) IsZero() bool {
+	return len(a.Command) == 0 && a.URL == ""
+}
+
+// approvalRequest is what the hook receives
+type approvalRequest struct {
+	Server      string         `json:"server,omitempty"`
+	Tool        string         `json:"tool"`
+	Arguments   any            `json:"arguments"`
+	Annotations map[string]any `json:"annotations,omitempty"`
+}
+
+// approvalDecision is what the hook answers. Decision is allow, deny or
+// modify; modify replaces the call's arguments with Arguments.
+type approvalDecision struct {
+	Decision  string         `json:"decision"`
+	Reason    string         `json:"reason,omitempty"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// approvalHandler holds tool calls that need approval until the hook decides.
+// It sits outside the override handler, so patterns, annotations and the hook
+// use the exposed tool names. The hook is shown the arguments with the
+// override's defaults and forced values applied; the override handler applies
+// them again after a modify, so the hook cannot change forced values.
+type approvalHandler struct {
+	next      Handler
+	config    Approval
+	tools     *matcher
+	server    string
+	overrides map[string]ToolOverride // Overrides that inject arguments, by exposed name
+	logf      func(format string, v ...interface{})
+	decide    func(ctx context.Context, req approvalRequest) (approvalDecision, error)
+	timeout   time.Duration
+
+	mu          sync.Mutex
+	annotations map[string]map[string]any // From tools/list, by tool name
+	inputs      map[string]*toolSchema    // Checked against modified arguments
+}
+
+func newApprovalHandler(next Handler, config Approval, server string, overrides map[string]ToolOverride, logf func(string, ...interface{})) (Handler, error) {
+	if config.IsZero() {
+		return next, nil
+	}
+	tools, err := Filter{Allow: config.Tools}.compile()
+	if err != nil {
+		return nil, fmt.Errorf("approval: %w", err)
+	}
+	if tools == nil && !config.NotReadOnly {
+		return nil, fmt.Errorf("approval: select tools by pattern or require approval for tools that are not read-only")
+	}
+	h := &approvalHandler{
+		next:        next,
+		config:      config,
+		tools:       tools,
+		server:      server,
+		logf:        logf,
+		timeout:     config.Timeout,
+		overrides:   map[string]ToolOverride{},
+		annotations: map[string]map[string]any{},
+		inputs:      map[string]*toolSchema{},
+	}
+	for original, o := range overrides {
+		if !o.injects() {
+			continue
+		}
+		if o.Name != "" {
+			original = o.Name
+		}
+		h.overrides[original] = o
+	}
+	if h.timeout <= 0 {
+		h.timeout = defaultApprovalTimeout
+	}
+	if len(config.Command) > 0 {
+		h.decide = h.runCommand
+	} else {
+		h.decide = h.postHook
+	}
+	return h, nil
+}
+
+// Handle implements Handler
+func (h *approvalHandler) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	switch req.Method {
+	case "tools/list":
+		resp, err := h.next.Handle(ctx, req)
+		if err == nil {
+			h.learn(req, resp)
+		}
+		return resp, err
+	case "tools/call":
+	default:
+		return h.next.Handle(ctx, req)
+	}
+
+	params, err := decodeParams(req)
+	if err != nil {
+		return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, err.Error(), nil), nil
+	}
+	name, _ := params["name"].(string)
+	h.mu.Lock()
+	annotations, listed := h.annotations[name]
+	h.mu.Unlock()
+	if !h.needsApproval(name, annotations, listed) {
+		return h.next.Handle(ctx, req)
+	}
+
+	var args any = map[string]any{}
+	if params["arguments"] != nil {
+		args = params["arguments"]
+	}
+	override, injects := h.overrides[name]
+	if injects {
+		args = override.inject(args)
+	}
+	decideCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	decision, err := h.decide(decideCtx, approvalRequest{Server: h.server, Tool: name, Arguments: args, Annotations: annotations})
+	cancel()
+	if err != nil {
+		decision = approvalDecision{Decision: "deny", Reason: fmt.Sprintf("approval hook failed: %v", err)}
+	}
+
+	switch decision.Decision {
+	case "allow":
+		h.logf("Approval hook allowed tool %s", name)
+	case "modify":
+		modified := map[string]any{}
+		for key, value := range decision.Arguments {
+			modified[key] = value
+		}
+		// Forced values are applied again by the override handler
+		for key, forced := range override.Forced {
+			if value, ok := modified[key]; ok && !sameJSON(value, forced) {
+				h.logf("Approval hook cannot change forced argument %s of tool %s", key, name)
+			}
+			delete(modified, key)
+		}
+		h.mu.Lock()
+		input := h.inputs[name]
+		h.mu.Unlock()
+		if input != nil {
+			if errs := input.validate(modified); len(errs) > 0 {
+				h.logf("Approval hook returned invalid arguments for tool %s: %s", name, joinSchemaErrors(errs))
+				return deniedResult(req, "Tool call denied: the approval hook returned invalid arguments: "+joinSchemaErrors(errs))
+			}
+		}
+		h.logf("Approval hook modified the arguments of tool %s", name)
+		params["arguments"] = modified
+		req = withParams(req, params)
+	default:
+		h.logf("Approval hook denied tool %s: %s", name, decision.Reason)
+		text := "Tool call denied by approval hook"
+		if decision.Reason != "" {
+			text += ": " + decision.Reason
+		}
+		return deniedResult(req, text)
+	}
+	return h.next.Handle(ctx, req)
+}
+
+// sameJSON reports whether a and b encode to the same JSON, so a forced 1
+// from the configuration equals a 1.0 decoded from the hook
+func sameJSON(a, b any) bool {
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(x, y)
+}
+
+// deniedResult answers a call the bridge did not forward with an error
+// result, so the model sees why
+func deniedResult(req *jsonrpc.Request, text string) (*jsonrpc.Response, error) {
+	return newResult(req.ID, map[string]any{
+		"isError": true,
+		"content": []any{map[string]any{"type": "text", "text": text}},
+	})
+}
+
+// needsApproval applies the tool patterns and the read-only rule. Tools the
+// client has not listed count as not read-only.
+func (h *approvalHandler) needsApproval(name string, annotations map[string]any, listed bool) bool {
+	if h.tools != nil && h.tools.visible(name) {
+		return true
+	}
+	if h.config.NotReadOnly {
+		readOnly, _ := annotations["readOnlyHint"].(bool)
+		return !listed || !readOnly
+	}
+	return false
+}
+
+// learn records tool annotations and input schemas from a page of tools/list
+func (h *approvalHandler) learn(req *jsonrpc.Request, resp *jsonrpc.Response) {
+	if resp == nil || resp.Error != nil {
+		return
+	}
+	var result struct {
+		Tools []struct {
+			Name        string          `json:"name"`
+			Annotations map[string]any  `json:"annotations"`
+			InputSchema json.RawMessage `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return
+	}
+	params, _ := decodeParams(req)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, paged := params["cursor"]; !paged {
+		h.annotations = map[string]map[string]any{}
+		h.inputs = map[string]*toolSchema{}
+	}
+	for _, tool := range result.Tools {
+		h.annotations[tool.Name] = tool.Annotations
+		if len(tool.InputSchema) > 0 {
+			if input, err := compileSchema(tool.InputSchema); err == nil {
+				h.inputs[tool.Name] = input
+			}
+		}
+	}
+}
+
+// runCommand runs the hook command with the call on stdin. A JSON decision on
+// stdout wins; otherwise exit status 0 allows and anything else denies, with
+// stderr as the reason.
+func (h *approvalHandler) runCommand(ctx context.Context, req approvalRequest) (approvalDecision, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return approvalDecision{}, err
+	}
+	cmd := exec.CommandContext(ctx, h.config.Command[0], h.config.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	cmd.WaitDelay = time.Second
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		return approvalDecision{}, ctx.Err()
+	}
+
+	if out := bytes.TrimSpace(stdout.Bytes()); len(out) > 0 {
+		return parseDecision(out)
+	}
+	if runErr != nil {
+		return approvalDecision{Decision: "deny", Reason: strings.TrimSpace(stderr.String())}, nil
+	}
+	return approvalDecision{Decision: "allow"}, nil
+}
+
+// postHook posts the call to the hook endpoint and reads its decision
+func (h *approvalHandler) postHook(ctx context.Context, req approvalRequest) (approvalDecision, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return approvalDecision{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", h.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return approvalDecision{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return approvalDecision{}, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return approvalDecision{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return approvalDecision{}, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return parseDecision(data)
+}
+
+func parseDecision(data []byte) (approvalDecision, error) {
+	var d approvalDecision
+	if err := json.Unmarshal(data, &d); err != nil {
+		return d, fmt.Errorf("invalid decision: %w", err)
+	}
+	switch d.Decision {
+	case "allow", "deny":
+	case "modify":
+		if d.Arguments == nil {
+			return d, fmt.Errorf("modify decision without arguments")
+		}
+	default:
+		return d, fmt.Errorf("unknown decision %q", d.Decision)
+	}
+	return d, nil
+}
@@ -0,0 +1,161 @@
+package bridge
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func approvalRemote() *fakeMCPServer {
+	return &fakeMCPServer{
+		name: "remote",
+		tools: []map[string]any{
+			{"name": "search", "annotations": map[string]any{"readOnlyHint": true}},
+			{"name": "deploy"},
+		},
+	}
+}
+
+func TestApprovalCommand(t *testing.T) {
+	remote := approvalRemote()
+	h, err := newApprovalHandler(remote, Approval{
+		Tools:   []string{"deploy"},
+		Command: []string{"sh", "-c", `if grep -q '"env":"prod"'; then echo "production is frozen" >&2; exit 1; fi`},
+	}, "ops", nil, t.Logf)
+	if err != nil {
+		t.Fatalf("newApprovalHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"deploy","arguments":{"env":"prod"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"deploy","arguments":{"env":"dev"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search"}}`,
+	)
+
+	denied := responses[0]["result"].(map[string]any)
+	text := denied["content"].([]any)[0].(map[string]any)["text"].(string)
+	if denied["isError"] != true || !strings.Contains(text, "production is frozen") {
+		t.Errorf("Expected denial with the hook's reason, got %v", denied)
+	}
+	if got := strings.Join(remote.calls, ","); got != "tools/call deploy,tools/call search" {
+		t.Errorf("Expected only approved and unselected calls to be forwarded, got %s", got)
+	}
+}
+
+func TestApprovalHTTP(t *testing.T) {
+	var received []approvalRequest
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req approvalRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		received = append(received, req)
+		json.NewEncoder(w).Encode(approvalDecision{Decision: "modify", Arguments: map[string]any{"env": "staging"}})
+	}))
+	defer hook.Close()
+
+	remote := approvalRemote()
+	h, err := newApprovalHandler(remote, Approval{NotReadOnly: true, URL: hook.URL}, "ops", nil, t.Logf)
+	if err != nil {
+		t.Fatalf("newApprovalHandler failed: %v", err)
+	}
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"search"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"deploy","arguments":{"env":"prod"}}}`,
+	)
+
+	if len(received) != 1 || received[0].Tool != "deploy" || received[0].Server != "ops" {
+		t.Fatalf("Expected only the tool without readOnlyHint to be sent for approval, got %+v", received)
+	}
+	text := responses[2]["result"].(map[string]any)["content"].([]any)[0].(map[string]any)["text"]
+	if text != "remote ran deploy with map[env:staging]" {
+		t.Errorf("Expected modified arguments to be forwarded, got %v", text)
+	}
+}
+
+func TestApprovalFailsClosed(t *testing.T) {
+	remote := approvalRemote()
+	h, _ := newApprovalHandler(remote, Approval{
+		Tools:   []string{"*"},
+		Command: []string{"sleep", "5"},
+		Timeout: 50 * time.Millisecond,
+	}, "", nil, t.Logf)
+	responses := runStdio(t, h, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"deploy"}}`)
+	if responses[0]["result"].(map[string]any)["isError"] != true || len(remote.calls) != 0 {
+		t.Errorf("Expected a timed out hook to deny the call, got %v", responses[0])
+	}
+
+	if _, err := newApprovalHandler(remote, Approval{URL: "http://localhost"}, "", nil, t.Logf); err == nil {
+		t.Error("Expected error when no tools are selected for approval")
+	}
+}
+
+func TestApprovalWithOverrides(t *testing.T) {
+	var received []approvalRequest
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req approvalRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		received = append(received, req)
+		// Try to lift the forced dry run
+		json.NewEncoder(w).Encode(approvalDecision{Decision: "modify", Arguments: map[string]any{"env": "prod", "dryRun": false}})
+	}))
+	defer hook.Close()
+	remote := httptest.NewServer(approvalRemote())
+	defer remote.Close()
+
+	b := New(remote.URL, "", false)
+	b.Approval = Approval{Tools: []string{"release"}, URL: hook.URL}
+	b.ToolOverrides = map[string]ToolOverride{
+		"deploy": {Name: "release", Defaults: map[string]any{"env": "staging"}, Forced: map[string]any{"dryRun": true}},
+	}
+	h, err := b.postHandler()
+	if err != nil {
+		t.Fatalf("postHandler failed: %v", err)
+	}
+	responses := runStdio(t, h, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"release","arguments":{"dryRun":false}}}`)
+
+	if len(received) != 1 {
+		t.Fatalf("Expected the exposed name to select the call for approval, got %+v", received)
+	}
+	want := map[string]any{"env": "staging", "dryRun": true}
+	if got := received[0]; got.Tool != "release" || !reflect.DeepEqual(got.Arguments, want) {
+		t.Errorf("Expected the hook to see the exposed name and final arguments, got %+v", got)
+	}
+	text := responses[0]["result"].(map[string]any)["content"].([]any)[0].(map[string]any)["text"]
+	if text != "remote ran deploy with map[dryRun:true env:prod]" {
+		t.Errorf("Expected the modified call to keep its forced argument, got %v", text)
+	}
+}
+
+func TestApprovalValidatesModifiedArguments(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(approvalDecision{Decision: "modify", Arguments: map[string]any{"env": 1}})
+	}))
+	defer hook.Close()
+
+	remote := approvalRemote()
+	remote.tools[1]["inputSchema"] = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"env": map[string]any{"type": "string"}},
+	}
+	h, err := newApprovalHandler(remote, Approval{Tools: []string{"deploy"}, URL: hook.URL}, "ops", nil, t.Logf)
+	if err != nil {
+		t.Fatalf("newApprovalHandler failed: %v", err)
+	}
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"deploy","arguments":{"env":"prod"}}}`,
+	)
+
+	result := responses[1]["result"].(map[string]any)
+	text := result["content"].([]any)[0].(map[string]any)["text"].(string)
+	if result["isError"] != true || !strings.Contains(text, "invalid arguments") {
+		t.Errorf("Expected invalid modified arguments to be rejected, got %v", result)
+	}
+	if got := strings.Join(remote.calls, ","); got != "tools/list" {
+		t.Errorf("Expected the call not to be forwarded, got %s", got)
+	}
+}
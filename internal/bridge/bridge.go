@@ -2,40 +2,187 @@ package bridge
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 type addAuthTransport struct {
-	base   http.RoundTripper
-	apiKey string
+	base http.RoundTripper
+	keys *keyPool
 }
 
+// RoundTrip authenticates the request with the active API key. When the server
+// answers 401 or 429 the key is put into cooldown and the request is retried
+// with the next key, as long as there is one and the body can be replayed.
 func (t *addAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", t.apiKey))
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Transfer-Encoding", "chunked")
+	for attempt := 1; ; attempt++ {
+		idx, key := t.keys.active()
+		authReq := req.Clone(req.Context())
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			authReq.Body = body
+		}
+		authReq.Header.Add("Authorization", fmt.Sprintf("Bearer %s", key))
+		authReq.Header.Add("Accept", "application/json")
+		authReq.Header.Add("Content-Type", "application/json")
+		authReq.Header.Add("Transfer-Encoding", "chunked")
+
+		resp, err := t.base.RoundTrip(authReq)
+		if err != nil || !retryWithNextKey(resp) {
+			return resp, err
+		}
+
+		t.keys.reject(idx, resp.StatusCode, parseRetryAfter(resp.Header.Get("Retry-After"), t.keys.now()))
+		replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if attempt >= len(t.keys.keys) || !replayable {
+			return resp, nil
+		}
+		drain(resp)
+	}
+}
+
+// headerTransport adds fixed headers to every request
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
 	return t.base.RoundTrip(req)
 }
 
+// Transport modes for reaching the remote server
+const (
+	TransportAuto      = "auto"      // Try streaming, fall back to HTTP POST
+	TransportStreaming = "streaming" // Streaming only
+	TransportPost      = "post"      // HTTP POST only
+)
+
+// defaultConnectTimeout bounds the streaming transport probe
+const defaultConnectTimeout = 3 * time.Second
+
 // MCPBridge manages bidirectional communication between a local stdio MCP client
 // and a remote HTTP MCP server.
 type MCPBridge struct {
+	Name        string // Identifies the server when aggregating several remotes
 	RemoteURL   string
 	APIKey      string
 	Debug       bool // Global debug flag (enables all debugging)
 	DebugClient bool // Enable client-side message logging
 	DebugServer bool // Enable server-side message logging
-	server      *mcp.Server
-	client      *mcp.Client
-	ctx         context.Context
+
+	// Logger receives the bridge's diagnostics. Message traces are logged at
+	// debug level when the matching debug flag is set. Default: text on the
+	// standard log output, at debug level when any debug flag is set.
+	Logger *slog.Logger
+
+	// APIKey may hold several comma-separated keys; a key answered with 401
+	// or 429 is skipped for KeyCooldown (or the server's Retry-After)
+	KeyCooldown time.Duration
+
+	Transport      string            // TransportAuto (default), TransportStreaming or TransportPost
+	Headers        map[string]string // Extra headers sent with every request
+	ConnectTimeout time.Duration     // Streaming probe timeout (default 3s)
+	RequestTimeout time.Duration     // Per-request timeout for HTTP POST (default none)
+
+	// FailoverURLs are tried in order when RemoteURL fails with a connection,
+	// TLS or 5xx error; preferred URLs are health checked every
+	// HealthCheckInterval (default 30s) so the bridge can fail back
+	FailoverURLs        []string
+	HealthCheckInterval time.Duration
+
+	// Lazy defers connecting until the first request that needs the remote,
+	// answering initialize from the result cached in CacheDir (default: the
+	// user cache directory) by the last successful session
+	Lazy     bool
+	CacheDir string
+
+	// Filters hide tools and prompts by name and resources by URI
+	ToolFilter     Filter
+	PromptFilter   Filter
+	ResourceFilter Filter
+
+	// ToolOverrides renames remote tools and rewrites their definitions,
+	// keyed by remote tool name. Filters match remote names.
+	ToolOverrides map[string]ToolOverride
+
+	// ValidateArguments checks tools/call arguments against the tool's
+	// inputSchema before forwarding
+	ValidateArguments bool
+
+	// OutputValidation checks structuredContent against the tool's
+	// outputSchema: OutputValidationOff (default), OutputValidationLog,
+	// OutputValidationAnnotate or OutputValidationError
+	OutputValidation string
+
+	// ReadOnly exposes only tools annotated readOnlyHint, never tools
+	// annotated destructiveHint, and tools without a readOnlyHint only when
+	// they match a ReadOnlyAllow glob pattern
+	ReadOnly      bool
+	ReadOnlyAllow []string
+
+	// Approval holds selected tool calls until an external hook allows,
+	// denies or modifies them
+	Approval Approval
+
+	// Policy rules are evaluated against every client request before any
+	// other processing except Transforms
+	Policy []PolicyRule
+
+	// Transforms pipe requests and responses through external commands,
+	// outside every other stage so they see what the client sends and gets
+	Transforms []Transform
+
+	// RateLimits throttles requests globally, per method and per tool
+	RateLimits RateLimits
+
+	// MaxRequestSize rejects larger client messages and MaxResponseSize
+	// stops reading larger remote responses, in bytes (0 means unlimited).
+	// With TruncateResponses an oversized tool result is cut short instead
+	// of failing.
+	MaxRequestSize    int64
+	MaxResponseSize   int64
+	TruncateResponses bool
+
+	// OffloadThreshold keeps tool results larger than this many bytes in
+	// the bridge and sends the client a preview and a resource_link it
+	// reads in pages of OffloadPageSize bytes (default: the threshold)
+	OffloadThreshold int64
+	OffloadPageSize  int64
+
+	// Redact masks secrets in debug logs, in addition to the credentials
+	// configured on the bridge
+	Redact Redaction
+
+	TLS    TLSOptions
+	Proxy  ProxyOptions
+	Signer Signer // Optional request signing (HMAC, SigV4)
+	server *mcp.Server
+	client *mcp.Client
+	ctx    context.Context
+
+	redactOnce sync.Once
+	redact     *redactor
+	redactErr  error
+
+	closers []io.Closer // Background work started by postHandler
 }
 
 func New(remoteURL, apiKey string, debug bool) *MCPBridge {
@@ -69,25 +216,36 @@ func (b *MCPBridge) SetDebugFlags(debugClient, debugServer bool) {
 	b.DebugServer = b.Debug || debugServer
 }
 
-// LogClient logs client-side messages with → indicator
+// logger returns the configured logger, tagged with the server name when
+// the bridge has one
+func (b *MCPBridge) logger() *slog.Logger {
+	l := b.Logger
+	if l == nil {
+		l = fallbackLogger(b.Debug || b.DebugClient || b.DebugServer)
+	}
+	if b.Name != "" {
+		l = l.With("server", b.Name)
+	}
+	return l
+}
+
+// LogClient logs client-side messages at debug level with direction out
 func (b *MCPBridge) LogClient(format string, v ...interface{}) {
 	if b.Debug || b.DebugClient {
-		log.Printf("→ "+format, v...)
+		b.logger().Debug(fmt.Sprintf(format, v...), "direction", DirectionOut)
 	}
 }
 
-// LogServer logs server-side messages with ← indicator
+// LogServer logs server-side messages at debug level with direction in
 func (b *MCPBridge) LogServer(format string, v ...interface{}) {
 	if b.Debug || b.DebugServer {
-		log.Printf("← "+format, v...)
+		b.logger().Debug(fmt.Sprintf(format, v...), "direction", DirectionIn)
 	}
 }
 
-// Log logs general messages (not specific to client/server)
+// Log logs general messages (not specific to client/server) at debug level
 func (b *MCPBridge) Log(format string, v ...interface{}) {
-	if b.Debug || b.DebugClient || b.DebugServer {
-		log.Printf(format, v...)
-	}
+	b.logger().Debug(fmt.Sprintf(format, v...))
 }
 
 // formatMCPMessage formats an MCP protocol message for logging
@@ -96,25 +254,55 @@ func (b *MCPBridge) formatMCPMessage(msg interface{}) string {
 		return "<nil>"
 	}
 
-	// Try to marshal the message to JSON for readability
-	json, err := json.MarshalIndent(msg, "", "  ")
+	// Try to marshal the message to JSON for readability, masking secrets
+	r, _ := b.redactor()
+	formatted, err := r.Value(msg)
 	if err != nil {
-		return fmt.Sprintf("%+v", msg)
+		return r.String(fmt.Sprintf("%+v", msg))
+	}
+	return formatted
+}
+
+// redactor returns the redactor for this bridge's logs, built on first use
+func (b *MCPBridge) redactor() (*redactor, error) {
+	b.redactOnce.Do(func() {
+		b.redact, b.redactErr = newRedactor(b.Redact, b.credentials())
+	})
+	return b.redact, b.redactErr
+}
+
+// credentials lists the secrets the bridge is configured with
+func (b *MCPBridge) credentials() []string {
+	var secrets []string
+	for _, key := range strings.Split(b.APIKey, ",") {
+		secrets = append(secrets, strings.TrimSpace(key))
+	}
+	for name, value := range b.Headers {
+		if sensitiveName(name) {
+			secrets = append(secrets, value)
+		}
+	}
+	if signer, ok := b.Signer.(*HMACSigner); ok {
+		secrets = append(secrets, string(signer.Secret))
 	}
-	return string(json)
+	secrets = append(secrets, b.Proxy.Password)
+	for _, u := range append([]string{b.RemoteURL, b.Proxy.URL}, b.FailoverURLs...) {
+		secrets = append(secrets, urlSecrets(u)...)
+	}
+	return secrets
 }
 
 // LogMCPClient logs client-side MCP protocol messages
 func (b *MCPBridge) LogMCPClient(desc string, msg interface{}) {
 	if b.Debug || b.DebugClient {
-		log.Printf("→ %s:\n%s", desc, b.formatMCPMessage(msg))
+		b.logger().Debug(desc, "direction", DirectionOut, messageAttr(b.formatMCPMessage(msg)))
 	}
 }
 
 // LogMCPServer logs server-side MCP protocol messages
 func (b *MCPBridge) LogMCPServer(desc string, msg interface{}) {
 	if b.Debug || b.DebugServer {
-		log.Printf("← %s:\n%s", desc, b.formatMCPMessage(msg))
+		b.logger().Debug(desc, "direction", DirectionIn, messageAttr(b.formatMCPMessage(msg)))
 	}
 }
 
@@ -142,53 +330,270 @@ func (b *MCPBridge) setupHttpFallback(client *http.Client) mcp.Transport {
 	return &mcp.StdioTransport{}
 }
 
-func (b *MCPBridge) Run() error {
-	b.Log("Starting MCP bridge to %s (debug: global=%v, client=%v, server=%v)",
-		b.RemoteURL, b.Debug, b.DebugClient, b.DebugServer)
+// httpClient builds the HTTP client used for all requests to the remote server,
+// applying proxy and TLS settings and authentication. A non-empty socketPath
+// routes every connection through that Unix domain socket instead.
+func (b *MCPBridge) httpClient(socketPath string) (*http.Client, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if socketPath != "" {
+		base.Proxy = nil
+		base.DialContext = unixDialer(socketPath)
+	} else {
+		proxy, err := b.Proxy.ProxyFunc()
+		if err != nil {
+			return nil, err
+		}
+		base.Proxy = proxy
+	}
+	if !b.TLS.IsZero() {
+		tlsConfig, err := b.TLS.Config()
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		base.TLSClientConfig = tlsConfig
+	}
 
+	// Signing runs last so it covers the custom headers and those added by
+	// authentication
+	keys := splitKeys(b.APIKey)
+	var rt http.RoundTripper = base
+	if b.Signer != nil {
+		if _, sigV4 := b.Signer.(*SigV4Signer); sigV4 && len(keys) > 0 {
+			return nil, errors.New("API keys cannot be used with SigV4 signing, whose Authorization header would replace the bearer token")
+		}
+		rt = &signTransport{base: rt, signer: b.Signer}
+	}
+	if len(b.Headers) > 0 {
+		rt = &headerTransport{base: rt, headers: b.Headers}
+	}
+	if len(keys) > 0 {
+		rt = &addAuthTransport{base: rt, keys: newKeyPool(keys, b.KeyCooldown, b.Log)}
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+// remoteEndpoint resolves the HTTP endpoint for RemoteURL and the client used
+// to reach it, dialing through a Unix socket for unix:// URLs
+func (b *MCPBridge) remoteEndpoint() (string, *http.Client, error) {
+	return b.endpointFor(b.RemoteURL)
+}
+
+// endpointFor resolves the HTTP endpoint and client for rawURL
+func (b *MCPBridge) endpointFor(rawURL string) (string, *http.Client, error) {
 	// Parse remote URL to determine transport type
-	remoteURL, err := url.Parse(b.RemoteURL)
+	remoteURL, err := url.Parse(rawURL)
 	if err != nil {
-		return fmt.Errorf("invalid remote URL: %v", err)
+		return "", nil, fmt.Errorf("invalid remote URL: %v", err)
 	}
 
-	// Connect to remote MCP server
-	var transport mcp.Transport
+	endpoint := rawURL
+	var socketPath string
 	switch remoteURL.Scheme {
 	case "http", "https":
-		// Create HTTP client with auth if needed
-		client := &http.Client{}
-		if b.APIKey != "" {
-			client.Transport = &addAuthTransport{base: http.DefaultTransport, apiKey: b.APIKey}
-		}
-
-		// Try streaming transport first
-		b.Log("Attempting streaming transport...")
-		streamingEndpoint := b.RemoteURL + "/stream"
-		streamTransport := &mcp.StreamableClientTransport{
-			Endpoint:   streamingEndpoint,
-			HTTPClient: client,
-		}
-
-		// Test streaming connection with timeout
-		testCtx, cancel := context.WithTimeout(b.ctx, 3*time.Second)
-		testSession, streamErr := b.client.Connect(testCtx, streamTransport, nil)
-		cancel()
-
-		if streamErr == nil {
-			testSession.Close()
-			b.Log("Using streaming transport")
-			transport = streamTransport
-		} else {
-			b.Log("Streaming not supported (%v), falling back to HTTP POST", streamErr)
-			// Fall back to HTTP POST transport
-			httpTransport := newHTTPPostTransport(b.RemoteURL, client, b.Debug)
-			// Run the HTTP POST bridge directly (it handles stdio itself)
-			return httpTransport.Run(b.ctx)
+	case "unix":
+		socketPath, endpoint, err = parseUnixEndpoint(remoteURL)
+		if err != nil {
+			return "", nil, err
+		}
+		b.Log("Using Unix socket %s", socketPath)
+	default:
+		return "", nil, fmt.Errorf("unsupported URL scheme: %s", remoteURL.Scheme)
+	}
+
+	client, err := b.httpClient(socketPath)
+	if err != nil {
+		return "", nil, err
+	}
+	return endpoint, client, nil
+}
+
+// postTransport creates the HTTP POST transport for endpoint, applying the
+// per-request timeout
+func (b *MCPBridge) postTransport(endpoint string, client *http.Client) *httpPostTransport {
+	if b.RequestTimeout > 0 {
+		timed := *client
+		timed.Timeout = b.RequestTimeout
+		client = &timed
+	}
+	t := newHTTPPostTransport(endpoint, client, b.Debug)
+	t.redact, _ = b.redactor()
+	t.logger = b.logger()
+	t.maxResponse, t.truncate = b.MaxResponseSize, b.TruncateResponses
+	return t
+}
+
+// close stops the background work of the handlers built by postHandler
+func (b *MCPBridge) close() {
+	for _, c := range b.closers {
+		c.Close()
+	}
+	b.closers = nil
+}
+
+// postHandler creates the Handler that forwards requests over HTTP POST,
+// failing over across RemoteURL and FailoverURLs when any are configured
+func (b *MCPBridge) postHandler() (Handler, error) {
+	redact, err := b.redactor()
+	if err != nil {
+		return nil, err
+	}
+	var h Handler
+	if len(b.FailoverURLs) == 0 {
+		endpoint, client, err := b.remoteEndpoint()
+		if err != nil {
+			return nil, err
+		}
+		h = b.postTransport(endpoint, client)
+	} else {
+		urls := append([]string{b.RemoteURL}, b.FailoverURLs...)
+		f, err := newFailover(urls, func(u string) (*httpPostTransport, error) {
+			endpoint, client, err := b.endpointFor(u)
+			if err != nil {
+				return nil, err
+			}
+			return b.postTransport(endpoint, client), nil
+		}, b.HealthCheckInterval)
+		if err != nil {
+			return nil, err
+		}
+		f.redact, f.logger = redact, b.logger()
+		b.closers = append(b.closers, f)
+		h = f
+	}
+
+	if b.Lazy {
+		cachePath, err := capabilityCachePath(b.CacheDir, b.RemoteURL)
+		if err != nil {
+			return nil, fmt.Errorf("no capability cache directory: %w", err)
+		}
+		lazy := newLazyConnect(h, cachePath, b.Log)
+		lazy.logger = b.logger()
+		h = lazy
+	}
+	// Every handler outside the overrides, from the filters on, matches the
+	// exposed tool names
+	if h, err = newOverrideHandler(h, b.ToolOverrides); err != nil {
+		return nil, err
+	}
+	h, err = newFilterHandler(h, b.ToolFilter, b.PromptFilter, b.ResourceFilter)
+	if err != nil {
+		return nil, err
+	}
+	// Approval runs outside the overrides, which apply forced arguments
+	// again after the hook modifies a call
+	if h, err = newApprovalHandler(h, b.Approval, b.Name, b.ToolOverrides, b.Log); err != nil {
+		return nil, err
+	}
+	if h, err = newReadOnlyHandler(h, b.ReadOnly, b.ReadOnlyAllow); err != nil {
+		return nil, err
+	}
+	if h, err = newSchemaHandler(h, b.ValidateArguments, b.OutputValidation, b.logger(), b.Log); err != nil {
+		return nil, err
+	}
+	h = newOffloadHandler(h, b.OffloadThreshold, b.OffloadPageSize, b.Log)
+	if h, err = newRateLimiter(h, b.RateLimits, b.Log); err != nil {
+		return nil, err
+	}
+	if h, err = newPolicyHandler(h, b.Policy, b.Name, b.Log); err != nil {
+		return nil, err
+	}
+	return newTransformHandler(h, b.Transforms, b.Log)
+}
+
+// postOnlyOptions names the configured options that only work over HTTP
+// POST: failover and lazy connection, which own the connection, and every
+// option implemented in the handler chain built by postHandler
+func (b *MCPBridge) postOnlyOptions() []string {
+	var options []string
+	add := func(set bool, name string) {
+		if set {
+			options = append(options, name)
 		}
+	}
+	add(len(b.FailoverURLs) > 0, "failover URLs")
+	add(b.Lazy, "lazy connection")
+	add(!b.ToolFilter.IsZero() || !b.PromptFilter.IsZero() || !b.ResourceFilter.IsZero(), "filters")
+	add(len(b.ToolOverrides) > 0, "tool overrides")
+	add(b.ReadOnly, "read-only mode")
+	add(!b.Approval.IsZero(), "approval")
+	add(b.ValidateArguments || b.OutputValidation != OutputValidationOff, "schema validation")
+	add(b.OffloadThreshold > 0, "result offloading")
+	add(!b.RateLimits.IsZero(), "rate limits")
+	add(b.MaxRequestSize > 0 || b.MaxResponseSize > 0, "size limits")
+	add(len(b.Policy) > 0, "policy rules")
+	add(len(b.Transforms) > 0, "transforms")
+	return options
+}
+
+func (b *MCPBridge) Run() error {
+	redact, err := b.redactor()
+	if err != nil {
+		return err
+	}
+	b.Log("Starting MCP bridge to %s (debug: global=%v, client=%v, server=%v)",
+		redact.String(b.RemoteURL), b.Debug, b.DebugClient, b.DebugServer)
+
+	endpoint, client, err := b.remoteEndpoint()
+	if err != nil {
+		return err
+	}
+
+	switch b.Transport {
+	case "", TransportAuto, TransportStreaming:
+	case TransportPost:
 	default:
-		return fmt.Errorf("unsupported URL scheme: %s", remoteURL.Scheme)
+		return fmt.Errorf("unsupported transport: %s", b.Transport)
+	}
+
+	// Streaming proxies messages untouched, so options that need to see
+	// them skip the streaming probe
+	if options := b.postOnlyOptions(); b.Transport == TransportPost || len(options) > 0 {
+		if b.Transport == TransportStreaming {
+			return fmt.Errorf("%s cannot be used with the streaming transport: use -transport post or auto", strings.Join(options, ", "))
+		}
+		h, err := b.postHandler()
+		if err != nil {
+			return err
+		}
+		defer b.close()
+		b.Log("Using HTTP POST transport")
+		return serveStdio(b.ctx, os.Stdin, os.Stdout, h, b.logger(), redact, b.MaxRequestSize)
+	}
+
+	// Try streaming transport first
+	b.Log("Attempting streaming transport...")
+	streamingEndpoint := endpoint + "/stream"
+	streamTransport := &mcp.StreamableClientTransport{
+		Endpoint:   streamingEndpoint,
+		HTTPClient: client,
+	}
+
+	// Test streaming connection with timeout
+	connectTimeout := b.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+	testCtx, cancel := context.WithTimeout(b.ctx, connectTimeout)
+	testSession, streamErr := b.client.Connect(testCtx, streamTransport, nil)
+	cancel()
+
+	if streamErr != nil {
+		if b.Transport == TransportStreaming {
+			return fmt.Errorf("streaming transport unavailable: %w", streamErr)
+		}
+		b.Log("Streaming not supported (%s), falling back to HTTP POST", redact.String(streamErr.Error()))
+		// Fall back to HTTP POST transport, with policy, filters and the
+		// other request handlers in front of it
+		h, err := b.postHandler()
+		if err != nil {
+			return err
+		}
+		defer b.close()
+		return serveStdio(b.ctx, os.Stdin, os.Stdout, h, b.logger(), redact, b.MaxRequestSize)
 	}
+	testSession.Close()
+	b.Log("Using streaming transport")
+	transport := streamTransport
 
 	// Connect client to remote server
 	remoteSession, err := b.client.Connect(b.ctx, transport, nil)
@@ -0,0 +1,26 @@
+package bridge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunRejectsHandlerOptionsWithStreaming(t *testing.T) {
+	b := New("http://127.0.0.1:1", "", false)
+	b.Transport = TransportStreaming
+	if options := b.postOnlyOptions(); len(options) != 0 {
+		t.Fatalf("Expected no POST-only options by default, got %v", options)
+	}
+
+	b.ToolFilter = Filter{Deny: []string{"delete_*"}}
+	b.Transforms = []Transform{{Command: []string{"cat"}}}
+	err := b.Run()
+	if err == nil {
+		t.Fatal("Expected filters and transforms to be rejected with the streaming transport")
+	}
+	for _, want := range []string{"filters", "transforms", "streaming transport"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %q, got %v", want, err)
+		}
+	}
+}
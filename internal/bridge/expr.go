@@ -0,0 +1,555 @@
+package bridge
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// expr is a compiled policy expression evaluated against the fields of a
+// request. Values are JSON values: string, float64, bool, nil, []any and
+// map[string]any. Fields that are missing evaluate to nil.
+//
+// The language has string ("..", '..' or `..`), number (with unary minus),
+// boolean and null literals, lists ([a, b]), field paths (arguments.query, params["x-y"],
+// arguments.items[0]), the comparisons ==, !=, <, <=, >, >=, contains, in,
+// matches (glob) and =~ (regular expression), and the boolean operators
+// and/&&, or/|| and not/! with parentheses.
+type expr interface {
+	eval(env map[string]any) any
+}
+
+// parseExpr compiles src
+func parseExpr(src string) (expr, error) {
+	tokens, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// exprKeywords are identifiers that cannot name a field
+var exprKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "contains": true, "matches": true,
+	"in": true, "true": true, "false": true, "null": true,
+}
+
+func lexExpr(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'' || c == '`':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' && c != '`' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			text := src[i+1 : j]
+			if c != '`' {
+				text = unescapeString(text)
+			}
+			tokens = append(tokens, token{tokenString, text, i})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, src[i:j], i})
+			i = j
+		case isIdentByte(c) && !(c >= '0' && c <= '9'):
+			j := i
+			for j < len(src) && isIdentByte(src[j]) {
+				j++
+			}
+			tokens = append(tokens, token{tokenIdent, src[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "=~", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", ".", "-"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+			}
+			tokens = append(tokens, token{tokenOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(src)}), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// unescapeString resolves \\, \", \', \n and \t, keeping other backslashes
+// so regular expressions like "\d+" need no doubling
+func unescapeString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case '\\', '"', '\'':
+			b.WriteByte(s[i])
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() token { return p.tokens[p.pos] }
+
+// accept consumes the next token if it is one of the given operators or
+// keywords
+func (p *exprParser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOp && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		t := p.peek()
+		return fmt.Errorf("expected %q at offset %d, found %q", text, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{or: true, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (expr, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (expr, error) {
+	left, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "contains", "in", "matches", "=~")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	c := &compareExpr{op: op, left: left, right: right}
+	if op == "matches" || op == "=~" {
+		if lit, ok := right.(*literalExpr); ok {
+			pattern, ok := lit.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s needs a string pattern", op)
+			}
+			if c.re, err = compilePattern(op, pattern); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+func (p *exprParser) parseValue() (expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenString:
+		p.pos++
+		return &literalExpr{t.text}, nil
+	case tokenNumber:
+		p.pos++
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at offset %d", t.text, t.pos)
+		}
+		return &literalExpr{n}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			p.pos++
+			return &literalExpr{t.text == "true"}, nil
+		case "null":
+			p.pos++
+			return &literalExpr{nil}, nil
+		}
+		if exprKeywords[t.text] {
+			break
+		}
+		p.pos++
+		return p.parsePath(t.text)
+	case tokenOp:
+		switch t.text {
+		case "-":
+			p.pos++
+			x, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			if lit, ok := x.(*literalExpr); ok {
+				n, ok := lit.value.(float64)
+				if !ok {
+					return nil, fmt.Errorf("unary minus needs a number at offset %d", t.pos)
+				}
+				return &literalExpr{-n}, nil
+			}
+			return &negExpr{x}, nil
+		case "(":
+			p.pos++
+			e, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(")")
+		case "[":
+			p.pos++
+			list := &listExpr{}
+			if _, ok := p.accept("]"); ok {
+				return list, nil
+			}
+			for {
+				item, err := p.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if _, ok := p.accept(","); !ok {
+					return list, p.expect("]")
+				}
+			}
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+func (p *exprParser) parsePath(root string) (expr, error) {
+	path := &pathExpr{root: root}
+	for {
+		if _, ok := p.accept("."); ok {
+			t := p.peek()
+			if t.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a field name at offset %d", t.pos)
+			}
+			p.pos++
+			path.steps = append(path.steps, t.text)
+			continue
+		}
+		if _, ok := p.accept("["); ok {
+			t := p.peek()
+			switch t.kind {
+			case tokenString:
+				path.steps = append(path.steps, t.text)
+			case tokenNumber:
+				index, err := strconv.Atoi(t.text)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q at offset %d", t.text, t.pos)
+				}
+				path.steps = append(path.steps, index)
+			default:
+				return nil, fmt.Errorf("expected a field name or index at offset %d", t.pos)
+			}
+			p.pos++
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return path, nil
+	}
+}
+
+// compilePattern compiles the right-hand side of matches (a glob) or =~
+func compilePattern(op, pattern string) (*regexp.Regexp, error) {
+	if op == "matches" {
+		return compileGlob(pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+type literalExpr struct{ value any }
+
+func (e *literalExpr) eval(map[string]any) any { return e.value }
+
+type listExpr struct{ items []expr }
+
+func (e *listExpr) eval(env map[string]any) any {
+	values := make([]any, len(e.items))
+	for i, item := range e.items {
+		values[i] = item.eval(env)
+	}
+	return values
+}
+
+// pathExpr looks up a field; steps are object keys (string) or array
+// indexes (int)
+type pathExpr struct {
+	root  string
+	steps []any
+}
+
+func (e *pathExpr) eval(env map[string]any) any {
+	value := env[e.root]
+	for _, step := range e.steps {
+		switch s := step.(type) {
+		case string:
+			obj, _ := value.(map[string]any)
+			value = obj[s]
+		case int:
+			arr, _ := value.([]any)
+			if s < 0 || s >= len(arr) {
+				return nil
+			}
+			value = arr[s]
+		}
+	}
+	return value
+}
+
+// negExpr negates a number; anything else evaluates to nil
+type negExpr struct{ x expr }
+
+func (e *negExpr) eval(env map[string]any) any {
+	if n, ok := e.x.eval(env).(float64); ok {
+		return -n
+	}
+	return nil
+}
+
+type notExpr struct{ x expr }
+
+func (e *notExpr) eval(env map[string]any) any { return !truthy(e.x.eval(env)) }
+
+type logicalExpr struct {
+	or          bool
+	left, right expr
+}
+
+func (e *logicalExpr) eval(env map[string]any) any {
+	if truthy(e.left.eval(env)) == e.or {
+		return e.or
+	}
+	return truthy(e.right.eval(env))
+}
+
+type compareExpr struct {
+	op          string
+	left, right expr
+	re          *regexp.Regexp // Precompiled pattern for a literal right side
+}
+
+func (e *compareExpr) eval(env map[string]any) any {
+	left, right := e.left.eval(env), e.right.eval(env)
+	switch e.op {
+	case "==":
+		return reflect.DeepEqual(left, right)
+	case "!=":
+		return !reflect.DeepEqual(left, right)
+	case "contains":
+		return contains(left, right)
+	case "in":
+		return contains(right, left)
+	case "matches", "=~":
+		s, ok := left.(string)
+		if !ok {
+			return false
+		}
+		re := e.re
+		if re == nil {
+			pattern, ok := right.(string)
+			if !ok {
+				return false
+			}
+			var err error
+			if re, err = compilePattern(e.op, pattern); err != nil {
+				return false
+			}
+		}
+		return re.MatchString(s)
+	}
+
+	// Ordering compares two numbers or two strings
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+		cmp = compareOrdered(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return false
+	}
+	switch e.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// contains reports whether a string contains a substring, a list an element
+// or an object a key
+func contains(container, item any) bool {
+	switch c := container.(type) {
+	case string:
+		s, ok := item.(string)
+		return ok && strings.Contains(c, s)
+	case []any:
+		for _, element := range c {
+			if reflect.DeepEqual(element, item) {
+				return true
+			}
+		}
+	case map[string]any:
+		key, ok := item.(string)
+		if ok {
+			_, found := c[key]
+			return found
+		}
+	}
+	return false
+}
+
+// truthy reports whether a value counts as true in a boolean context: false,
+// null, zero, and empty strings, lists and objects do not
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != ""
+	case float64:
+		return x != 0
+	case []any:
+		return len(x) > 0
+	case map[string]any:
+		return len(x) > 0
+	}
+	return true
+}
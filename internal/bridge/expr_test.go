@@ -0,0 +1,68 @@
+package bridge
+
+import "testing"
+
+func TestExpressions(t *testing.T) {
+	env := map[string]any{
+		"method": "tools/call",
+		"tool":   "db_query",
+		"arguments": map[string]any{
+			"query": "DROP TABLE users",
+			"limit": float64(50),
+			"tags":  []any{"prod", "eu"},
+			"x-id":  "abc",
+		},
+		"client": map[string]any{"name": "cursor"},
+		"time":   map[string]any{"hour": float64(22), "weekday": "Saturday"},
+	}
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`method == "tools/call"`, true},
+		{`tool matches "db_*" and arguments.query contains "DROP"`, true},
+		{"tool matches `db_*` && arguments.query contains 'DELETE'", false},
+		{`arguments.query =~ "(?i)^drop\s"`, true},
+		{`arguments.limit > 10 and arguments.limit <= 50`, true},
+		{`arguments.tags[1] == "eu"`, true},
+		{`arguments["x-id"] == "abc"`, true},
+		{`"prod" in arguments.tags`, true},
+		{`client.name in ["cursor", "zed"]`, true},
+		{`time.weekday in ["Saturday", "Sunday"] or time.hour < 8`, true},
+		{`not (time.hour >= 9 and time.hour < 18)`, true},
+		{`!arguments.missing`, true},
+		{`arguments.missing == null`, true},
+		{`arguments.missing.deeper > 1`, false},
+		{`arguments.limit == "50"`, false},
+		{`arguments contains "query"`, true},
+		{`arguments.limit > -1 and -arguments.limit < -49`, true},
+		{`arguments.limit in [-50, 50]`, true},
+	}
+	for _, tt := range tests {
+		e, err := parseExpr(tt.src)
+		if err != nil {
+			t.Errorf("parseExpr(%s) failed: %v", tt.src, err)
+			continue
+		}
+		if got := truthy(e.eval(env)); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{
+		`method ==`,
+		`(method == "x"`,
+		`method == "x" extra`,
+		`"unterminated`,
+		`tool matches 5`,
+		`tool =~ "("`,
+		`arguments.`,
+		`method # "x"`,
+		`arguments.limit > -"1"`,
+	} {
+		if _, err := parseExpr(src); err == nil {
+			t.Errorf("Expected parseExpr(%s) to fail", src)
+		}
+	}
+}
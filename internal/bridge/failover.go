@@ -0,0 +1,274 @@
+package bridge
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// defaultHealthCheckInterval is how often a failed-over bridge checks whether
+// a preferred endpoint has recovered
+const defaultHealthCheckInterval = 30 * time.Second
+
+// failover is a Handler that sends every request to the first working
+// endpoint of an ordered list. Connection errors, TLS errors and 5xx
+// responses move it to the next endpoint, which is initialized with the
+// client's original initialize request. The failed request is retried there
+// only when it cannot have run twice: it never reached the server, or its
+// method only reads (see replayable). Otherwise the client gets the error
+// and its next request goes to the new endpoint.
+// While a fallback is active, preferred endpoints are health checked and the
+// bridge fails back as soon as one of them initializes again, until Close.
+// The lock only guards endpoint selection, so requests run concurrently.
+type failover struct {
+	urls     []string
+	connect  func(url string) (*httpPostTransport, error)
+	interval time.Duration
+	redact   *redactor    // Masks credentials in endpoint URLs and errors
+	logger   *slog.Logger // Default: slog.Default()
+
+	mu          sync.Mutex
+	endpoints   []*httpPostTransport
+	active      int
+	initReq     *jsonrpc.Request // Replayed on every new endpoint
+	initialized bool             // The client sent notifications/initialized
+	checking    bool             // Health check loop is running
+
+	stop     chan struct{} // Closed by Close to end the health check
+	stopOnce sync.Once
+}
+
+func newFailover(urls []string, connect func(string) (*httpPostTransport, error), interval time.Duration) (*failover, error) {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	f := &failover{urls: urls, connect: connect, interval: interval, stop: make(chan struct{})}
+	for _, u := range urls {
+		t, err := connect(u)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s: %w", u, err)
+		}
+		f.endpoints = append(f.endpoints, t)
+	}
+	return f, nil
+}
+
+// Handle implements Handler
+func (f *failover) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	f.mu.Lock()
+	switch req.Method {
+	case "initialize":
+		f.initReq = req
+	case "notifications/initialized":
+		f.initialized = true
+	}
+	idx, t := f.active, f.endpoints[f.active]
+	f.mu.Unlock()
+
+	var lastErr error
+	for tried := 0; tried < len(f.endpoints); tried++ {
+		if tried > 0 {
+			var err error
+			if idx, t, err = f.advance(ctx, idx, req.Method != "initialize"); err != nil {
+				lastErr = err
+				f.warn(idx, err)
+				continue
+			}
+		}
+
+		resp, err := t.post(ctx, req)
+		if !shouldFailover(ctx, err) {
+			return f.respond(req, resp, err)
+		}
+		lastErr = err
+		f.warn(idx, err)
+		if !replayable(req, err) {
+			// The server may have acted on the request, so it is not sent
+			// again; later requests go to the next endpoint
+			if idx, _, err := f.advance(ctx, idx, true); err != nil {
+				f.warn(idx, err)
+			}
+			return f.respond(req, nil, lastErr)
+		}
+	}
+	return f.respond(req, nil, lastErr)
+}
+
+// Close stops the health check
+func (f *failover) Close() error {
+	f.stopOnce.Do(func() { close(f.stop) })
+	return nil
+}
+
+// readMethods only read server state, so sending one twice is harmless
+var readMethods = map[string]bool{
+	"initialize": true, "ping": true,
+	"tools/list": true, "prompts/list": true, "prompts/get": true,
+	"resources/list": true, "resources/templates/list": true, "resources/read": true,
+	"completion/complete": true,
+}
+
+// replayable reports whether req may be retried on another endpoint after
+// failing with err: either it never reached the server, because dialing,
+// the proxy or the TLS handshake failed, or its method only reads
+func replayable(req *jsonrpc.Request, err error) bool {
+	if readMethods[req.Method] {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect") {
+		return true
+	}
+	var dnsErr *net.DNSError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var certErr *tls.CertificateVerificationError
+	return errors.As(err, &dnsErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &certErr)
+}
+
+func (f *failover) warn(idx int, err error) {
+	f.log().Warn("Endpoint failed", "endpoint", f.redact.String(f.urls[idx]), "error", f.redact.String(err.Error()))
+}
+
+func (f *failover) log() *slog.Logger {
+	if f.logger == nil {
+		return slog.Default()
+	}
+	return f.logger
+}
+
+// respond turns a *statusError into the error response the client sees
+// without failover
+func (f *failover) respond(req *jsonrpc.Request, resp *jsonrpc.Response, err error) (*jsonrpc.Response, error) {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return jsonrpc.NewError(req.ID, jsonrpc.InternalError, statusErr.Error(), nil), nil
+	}
+	return resp, err
+}
+
+// shouldFailover reports whether err means the endpoint is unavailable, as
+// opposed to the client giving up or the server rejecting the request
+func shouldFailover(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= 500
+	}
+	return true
+}
+
+// advance moves from the failed endpoint from to the next one, starting a new
+// session on it when reinit is set. When a concurrent request already moved
+// on, the endpoint it chose is returned instead.
+func (f *failover) advance(ctx context.Context, from int, reinit bool) (int, *httpPostTransport, error) {
+	f.mu.Lock()
+	if f.active != from {
+		idx, t := f.active, f.endpoints[f.active]
+		f.mu.Unlock()
+		return idx, t, nil
+	}
+	idx := (from + 1) % len(f.endpoints)
+	f.active = idx
+	f.log().Info(fmt.Sprintf("Switching to endpoint %d/%d", idx+1, len(f.urls)), "endpoint", f.redact.String(f.urls[idx]))
+	if idx != 0 && !f.checking {
+		f.checking = true
+		go f.healthCheck()
+	}
+	t, init, initialized := f.endpoints[idx], f.initReq, f.initialized
+	f.mu.Unlock()
+
+	t.resetSession()
+	if !reinit || init == nil {
+		return idx, t, nil
+	}
+	return idx, t, initSession(ctx, t, init, initialized)
+}
+
+// healthCheck runs while a fallback endpoint is active, periodically trying
+// to initialize a fresh session on each preferred endpoint and failing back
+// to the first one that succeeds. It ends on fail back or Close.
+func (f *failover) healthCheck() {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+		}
+		f.mu.Lock()
+		active, init, initialized := f.active, f.initReq, f.initialized
+		if active == 0 {
+			f.checking = false
+			f.mu.Unlock()
+			return
+		}
+		f.mu.Unlock()
+
+		if init == nil {
+			init = healthCheckRequest
+		}
+		for idx := 0; idx < active; idx++ {
+			t, err := f.connect(f.urls[idx])
+			if err != nil {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), f.interval)
+			err = initSession(ctx, t, init, initialized)
+			cancel()
+			if err != nil {
+				continue
+			}
+
+			f.mu.Lock()
+			if idx < f.active {
+				f.endpoints[idx] = t
+				f.active = idx
+				f.log().Info(fmt.Sprintf("Endpoint is healthy again, failing back (endpoint %d/%d)", idx+1, len(f.urls)), "endpoint", f.redact.String(f.urls[idx]))
+			}
+			f.mu.Unlock()
+			break
+		}
+	}
+}
+
+// initSession sends init to t and, if the client already completed its
+// handshake, the initialized notification
+func initSession(ctx context.Context, t *httpPostTransport, init *jsonrpc.Request, initialized bool) error {
+	resp, err := t.post(ctx, init)
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return errors.New("initialize failed: no response")
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("initialize failed: %s", resp.Error.Message)
+	}
+	if initialized {
+		_, err = t.post(ctx, &jsonrpc.Request{JSONRPC: jsonrpc.Version, Method: "notifications/initialized"})
+	}
+	return err
+}
+
+// healthCheckRequest initializes a session when the client has not yet sent
+// its own initialize request
+var healthCheckRequest = &jsonrpc.Request{
+	JSONRPC: jsonrpc.Version,
+	ID:      "health-check",
+	Method:  "initialize",
+	Params: json.RawMessage(`{"protocolVersion":"2025-06-18","capabilities":{},` +
+		`"clientInfo":{"name":"mcp-bridge","version":"v1.0.0"}}`),
+}
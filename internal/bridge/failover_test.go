@@ -0,0 +1,232 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// flakyServer serves an MCP server that can be switched to answer 503
+type flakyServer struct {
+	mu   sync.Mutex
+	down bool
+	mcp  *fakeMCPServer
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		http.Error(w, "regional outage", http.StatusServiceUnavailable)
+		return
+	}
+	s.mcp.ServeHTTP(w, r)
+}
+
+func (s *flakyServer) setDown(down bool) {
+	s.mu.Lock()
+	s.down = down
+	s.mu.Unlock()
+}
+
+func (s *flakyServer) calls() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.mcp.calls, ",")
+}
+
+func connectPost(u string) (*httpPostTransport, error) {
+	return newHTTPPostTransport(u, nil, false), nil
+}
+
+func TestFailover(t *testing.T) {
+	primary := &flakyServer{mcp: &fakeMCPServer{name: "primary"}}
+	secondary := &flakyServer{mcp: &fakeMCPServer{name: "secondary"}}
+	primarySrv := httptest.NewServer(primary)
+	defer primarySrv.Close()
+	secondarySrv := httptest.NewServer(secondary)
+	defer secondarySrv.Close()
+
+	f, err := newFailover([]string{primarySrv.URL, secondarySrv.URL}, connectPost, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("newFailover failed: %v", err)
+	}
+	defer f.Close()
+
+	responses := runStdio(t, f,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+	)
+	if len(responses) != 1 || responses[0]["result"] == nil {
+		t.Fatalf("Expected initialize result, got %v", responses)
+	}
+
+	// A tool call answered with 503 may have run, so it is not replayed,
+	// but the next one goes to the secondary
+	primary.setDown(true)
+	responses = runStdio(t, f,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"next"}}`,
+	)
+	if _, ok := responses[0]["error"].(map[string]any); !ok {
+		t.Errorf("Expected the failed call to be reported, got %v", responses[0])
+	}
+	content := responses[1]["result"].(map[string]any)["content"].([]any)
+	if text := content[0].(map[string]any)["text"].(string); !strings.HasPrefix(text, "secondary ran next") {
+		t.Errorf("Expected the next call to go to secondary, got %q", text)
+	}
+	if got := secondary.calls(); got != "initialize,notifications/initialized,tools/call next" {
+		t.Errorf("Expected secondary to be re-initialized and get only the next call, got %s", got)
+	}
+
+	primary.setDown(false)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		f.mu.Lock()
+		active := f.active
+		f.mu.Unlock()
+		if active == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected health check to fail back to primary")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	runStdio(t, f, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"again"}}`)
+	if got := primary.calls(); !strings.HasSuffix(got, "initialize,notifications/initialized,tools/call again") {
+		t.Errorf("Expected primary to get a new session after failing back, got %s", got)
+	}
+
+	// Listing only reads, so it is replayed
+	primary.setDown(true)
+	responses = runStdio(t, f, `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`)
+	if responses[0]["result"] == nil {
+		t.Errorf("Expected tools/list to be replayed on secondary, got %v", responses[0])
+	}
+	if got := secondary.calls(); !strings.HasSuffix(got, "tools/list") {
+		t.Errorf("Expected secondary to get tools/list, got %s", got)
+	}
+}
+
+func TestFailoverConnectionError(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	backup := &flakyServer{mcp: &fakeMCPServer{name: "backup"}}
+	backupSrv := httptest.NewServer(backup)
+	defer backupSrv.Close()
+
+	f, err := newFailover([]string{dead.URL, backupSrv.URL}, connectPost, time.Hour)
+	if err != nil {
+		t.Fatalf("newFailover failed: %v", err)
+	}
+	defer f.Close()
+	responses := runStdio(t, f, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	if responses[0]["result"] == nil {
+		t.Errorf("Expected initialize to succeed on backup, got %v", responses[0])
+	}
+
+	// The call never reached the dead endpoint, so it is safe to replay
+	f.mu.Lock()
+	f.active = 0
+	f.mu.Unlock()
+	responses = runStdio(t, f, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo"}}`)
+	if responses[0]["result"] == nil {
+		t.Errorf("Expected the call to be replayed on backup, got %v", responses[0])
+	}
+
+	backup.setDown(true)
+	responses = runStdio(t, f, `{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	if _, ok := responses[0]["error"].(map[string]any); !ok {
+		t.Errorf("Expected error once every endpoint is down, got %v", responses[0])
+	}
+}
+
+func TestFailoverInitializeWithoutResponse(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	// Accepts every message without answering, even initialize
+	silent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer silent.Close()
+
+	f, err := newFailover([]string{dead.URL, silent.URL}, connectPost, time.Hour)
+	if err != nil {
+		t.Fatalf("newFailover failed: %v", err)
+	}
+	defer f.Close()
+	f.initReq = healthCheckRequest
+
+	responses := runStdio(t, f, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo"}}`)
+	if _, ok := responses[0]["error"].(map[string]any); !ok {
+		t.Errorf("Expected an error when no endpoint initializes, got %v", responses[0])
+	}
+}
+
+func TestFailoverConcurrentRequests(t *testing.T) {
+	release := make(chan struct{})
+	mcp := &fakeMCPServer{name: "remote"}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "slow") {
+			<-release
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		mu.Lock()
+		defer mu.Unlock()
+		mcp.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	f, err := newFailover([]string{server.URL}, connectPost, time.Hour)
+	if err != nil {
+		t.Fatalf("newFailover failed: %v", err)
+	}
+	defer f.Close()
+
+	go f.Handle(context.Background(), &jsonrpc.Request{
+		JSONRPC: jsonrpc.Version, ID: 1, Method: "tools/call", Params: []byte(`{"name":"slow"}`),
+	})
+	done := make(chan []map[string]any)
+	go func() { done <- runStdio(t, f, `{"jsonrpc":"2.0","id":2,"method":"ping"}`) }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a request to go through while another one is in flight")
+	}
+}
+
+func TestFailoverCloseStopsHealthCheck(t *testing.T) {
+	primary := &flakyServer{mcp: &fakeMCPServer{name: "primary"}, down: true}
+	primarySrv := httptest.NewServer(primary)
+	defer primarySrv.Close()
+	secondarySrv := httptest.NewServer(&flakyServer{mcp: &fakeMCPServer{name: "secondary"}})
+	defer secondarySrv.Close()
+
+	f, err := newFailover([]string{primarySrv.URL, secondarySrv.URL}, connectPost, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("newFailover failed: %v", err)
+	}
+	runStdio(t, f, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	f.Close()
+	primary.setDown(false)
+	time.Sleep(100 * time.Millisecond)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.active != 1 {
+		t.Error("Expected no fail back once the failover is closed")
+	}
+}
@@ -0,0 +1,171 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// Filter hides tools, prompts or resources by glob pattern. "*" matches any
+// run of characters, including "/" in resource URIs, and "?" matches one.
+// With Allow set only matching items are visible; Deny always wins.
+type Filter struct {
+	Allow []string
+	Deny  []string
+}
+
+// IsZero reports whether the filter lets everything through
+func (f Filter) IsZero() bool {
+	return len(f.Allow) == 0 && len(f.Deny) == 0
+}
+
+// matcher is a compiled Filter
+type matcher struct {
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+func (f Filter) compile() (*matcher, error) {
+	if f.IsZero() {
+		return nil, nil
+	}
+	m := &matcher{}
+	for _, list := range []struct {
+		patterns []string
+		dst      *[]*regexp.Regexp
+	}{{f.Allow, &m.allow}, {f.Deny, &m.deny}} {
+		for _, pattern := range list.patterns {
+			re, err := compileGlob(pattern)
+			if err != nil {
+				return nil, err
+			}
+			*list.dst = append(*list.dst, re)
+		}
+	}
+	return m, nil
+}
+
+// compileGlob translates a glob pattern into an anchored regular expression
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// visible reports whether name passes the filter. A nil matcher allows all.
+func (m *matcher) visible(name string) bool {
+	if m == nil {
+		return true
+	}
+	for _, re := range m.deny {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(m.allow) == 0 {
+		return true
+	}
+	for _, re := range m.allow {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterHandler removes hidden tools, prompts and resources from list results
+// and rejects requests that name them with MethodNotFound, as if the remote
+// server did not offer them at all
+type filterHandler struct {
+	next      Handler
+	tools     *matcher
+	prompts   *matcher
+	resources *matcher
+}
+
+func newFilterHandler(next Handler, tools, prompts, resources Filter) (Handler, error) {
+	h := &filterHandler{next: next}
+	var err error
+	if h.tools, err = tools.compile(); err != nil {
+		return nil, fmt.Errorf("tool filter: %w", err)
+	}
+	if h.prompts, err = prompts.compile(); err != nil {
+		return nil, fmt.Errorf("prompt filter: %w", err)
+	}
+	if h.resources, err = resources.compile(); err != nil {
+		return nil, fmt.Errorf("resource filter: %w", err)
+	}
+	if h.tools == nil && h.prompts == nil && h.resources == nil {
+		return next, nil
+	}
+	return h, nil
+}
+
+// Handle implements Handler
+func (h *filterHandler) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	var m *matcher
+	var field, key, kind string
+	switch req.Method {
+	case "tools/list":
+		m, field, key = h.tools, "tools", "name"
+	case "prompts/list":
+		m, field, key = h.prompts, "prompts", "name"
+	case "resources/list":
+		m, field, key = h.resources, "resources", "uri"
+	case "resources/templates/list":
+		m, field, key = h.resources, "resourceTemplates", "uriTemplate"
+	case "tools/call":
+		m, key, kind = h.tools, "name", "tool"
+	case "prompts/get":
+		m, key, kind = h.prompts, "name", "prompt"
+	case "resources/read", "resources/subscribe", "resources/unsubscribe":
+		m, key, kind = h.resources, "uri", "resource"
+	}
+	if m == nil {
+		return h.next.Handle(ctx, req)
+	}
+
+	if kind != "" {
+		params, err := decodeParams(req)
+		if err != nil {
+			return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, err.Error(), nil), nil
+		}
+		if value, _ := params[key].(string); !m.visible(value) {
+			return jsonrpc.NewError(req.ID, jsonrpc.MethodNotFound, fmt.Sprintf("Unknown %s: %s", kind, value), nil), nil
+		}
+		return h.next.Handle(ctx, req)
+	}
+
+	resp, err := h.next.Handle(ctx, req)
+	editResult(resp, func(result map[string]any) {
+		items, _ := result[field].([]any)
+		kept := []any{}
+		for _, item := range items {
+			obj, _ := item.(map[string]any)
+			value, _ := obj[key].(string)
+			if m.visible(value) {
+				kept = append(kept, item)
+			}
+		}
+		result[field] = kept
+	})
+	return resp, err
+}
@@ -0,0 +1,76 @@
+package bridge
+
+import (
+	"strings"
+	"testing"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestFilterHandler(t *testing.T) {
+	remote := &fakeMCPServer{
+		name: "shared",
+		tools: []map[string]any{
+			{"name": "search_issues"},
+			{"name": "create_issue"},
+			{"name": "admin_delete_repo"},
+		},
+		resources: []map[string]any{
+			{"uri": "file:///docs/guide.md"},
+			{"uri": "file:///secrets/token.txt"},
+		},
+	}
+	h, err := newFilterHandler(remote,
+		Filter{Allow: []string{"*_issue*"}, Deny: []string{"create_*"}},
+		Filter{},
+		Filter{Deny: []string{"file:///secrets/*"}},
+	)
+	if err != nil {
+		t.Fatalf("newFilterHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"admin_delete_repo"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search_issues"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"resources/read","params":{"uri":"file:///secrets/token.txt"}}`,
+	)
+
+	var names []string
+	for _, tool := range responses[0]["result"].(map[string]any)["tools"].([]any) {
+		names = append(names, tool.(map[string]any)["name"].(string))
+	}
+	if got := strings.Join(names, ","); got != "search_issues" {
+		t.Errorf("Expected only search_issues to be listed, got %s", got)
+	}
+
+	for _, i := range []int{1, 4} {
+		errObj, ok := responses[i]["error"].(map[string]any)
+		if !ok || errObj["code"] != float64(jsonrpc.MethodNotFound) {
+			t.Errorf("Expected MethodNotFound for hidden item, got %v", responses[i])
+		}
+	}
+	if responses[2]["result"] == nil {
+		t.Errorf("Expected visible tool call to be forwarded, got %v", responses[2])
+	}
+
+	resources := responses[3]["result"].(map[string]any)["resources"].([]any)
+	if len(resources) != 1 || resources[0].(map[string]any)["uri"] != "file:///docs/guide.md" {
+		t.Errorf("Expected secrets to be hidden across path segments, got %v", resources)
+	}
+
+	for _, call := range remote.calls {
+		if strings.Contains(call, "admin_delete_repo") || strings.Contains(call, "secrets") {
+			t.Errorf("Hidden item reached the remote server: %s", call)
+		}
+	}
+}
+
+func TestFilterHandlerPassthrough(t *testing.T) {
+	remote := &fakeMCPServer{name: "remote"}
+	h, err := newFilterHandler(remote, Filter{}, Filter{}, Filter{})
+	if err != nil || h != Handler(remote) {
+		t.Errorf("Expected empty filters to leave the handler unwrapped, got %T (%v)", h, err)
+	}
+}
@@ -0,0 +1,202 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// Handler processes a JSON-RPC request from the stdio client and returns the
+// response to send back. Notifications have no ID and return a nil response.
+// An error means the request could not be handled at all and is reported to
+// the client as an internal error.
+type Handler interface {
+	Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error)
+}
+
+// HandlerFunc adapts a function to the Handler interface
+type HandlerFunc func(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error)
+
+// Handle implements Handler
+func (f HandlerFunc) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	return f(ctx, req)
+}
+
+// decodeParams decodes request params as a JSON object
+func decodeParams(req *jsonrpc.Request) (map[string]any, error) {
+	params := map[string]any{}
+	if len(req.Params) == 0 {
+		return params, nil
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %v", err)
+	}
+	return params, nil
+}
+
+// withParams returns a copy of req carrying params
+func withParams(req *jsonrpc.Request, params map[string]any) *jsonrpc.Request {
+	clone := *req
+	clone.Params, _ = json.Marshal(params)
+	return &clone
+}
+
+// newResult builds a success response carrying result
+func newResult(id interface{}, result any) (*jsonrpc.Response, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonrpc.Response{JSONRPC: jsonrpc.Version, ID: id, Result: data}, nil
+}
+
+// editResult decodes the result of a successful response as a JSON object,
+// lets edit change it in place and re-encodes it
+func editResult(resp *jsonrpc.Response, edit func(result map[string]any)) {
+	if resp == nil || resp.Error != nil {
+		return
+	}
+	var result map[string]any
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return
+	}
+	edit(result)
+	if data, err := json.Marshal(result); err == nil {
+		resp.Result = data
+	}
+}
+
+// serveStdio reads newline-delimited JSON-RPC requests from in, passes each
+// one to h and writes the responses to out. Requests are handled in order.
+// Messages longer than maxRequest bytes (0 means unlimited) are rejected
+// without being buffered. Problems with client messages are logged to logger
+// (default: slog.Default()) with secrets masked by redact.
+func serveStdio(ctx context.Context, in io.Reader, out io.Writer, h Handler, logger *slog.Logger, redact *redactor, maxRequest int64) error {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &stdioSession{handler: h, logger: logger, redact: redact}
+	reader := bufio.NewReader(in)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		line, tooLong, err := readLine(reader, maxRequest)
+		if tooLong {
+			logger.Warn("Rejecting client message over the size limit", "limit", maxRequest)
+			resp := jsonrpc.NewError(prefixID(line), jsonrpc.InvalidRequest, fmt.Sprintf("Message exceeds the %d byte limit", maxRequest), nil)
+			if err := writeMessage(out, resp); err != nil {
+				return fmt.Errorf("write error: %w", err)
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("read error: %w", err)
+			}
+			continue
+		}
+		if err != nil && (err != io.EOF || len(bytes.TrimSpace(line)) == 0) {
+			if err == io.EOF {
+				logger.Debug("EOF on stdin, shutting down")
+				return nil
+			}
+			return fmt.Errorf("read error: %w", err)
+		}
+
+		data := bytes.TrimSpace(line)
+		if len(data) == 0 {
+			continue
+		}
+
+		var writeErr error
+		if data[0] == '[' {
+			writeErr = s.serveBatch(ctx, out, data)
+		} else if resp := s.handleMessage(ctx, data); resp != nil {
+			writeErr = writeMessage(out, resp)
+		}
+		if writeErr != nil {
+			return fmt.Errorf("write error: %w", writeErr)
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// stdioSession holds what serveStdio needs to handle each client message
+type stdioSession struct {
+	handler Handler
+	logger  *slog.Logger
+	redact  *redactor
+}
+
+// handleMessage passes one client message to the handler and returns the
+// response to write, if any
+func (s *stdioSession) handleMessage(ctx context.Context, data []byte) *jsonrpc.Response {
+	msg, err := jsonrpc.Parse(data)
+	if err != nil {
+		s.logger.Warn("Invalid JSON received", "error", err)
+		return nil
+	}
+	req, ok := msg.(*jsonrpc.Request)
+	if !ok {
+		s.logger.Warn("Ignoring unexpected response from client", "direction", DirectionOut, messageAttr(s.redact.JSON(data)))
+		return nil
+	}
+
+	resp, err := s.handler.Handle(ctx, req)
+	if err != nil {
+		resp = jsonrpc.NewError(req.ID, jsonrpc.InternalError, fmt.Sprintf("Bridge error: %v", err), nil)
+	}
+	if req.ID == nil {
+		return nil
+	}
+	return resp
+}
+
+// serveBatch handles a JSON-RPC batch message by message, in order, and
+// answers with an array of the responses. A batch of notifications gets no
+// answer.
+func (s *stdioSession) serveBatch(ctx context.Context, out io.Writer, data []byte) error {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil || len(batch) == 0 {
+		s.logger.Warn("Invalid or empty batch received", "error", err)
+		return writeMessage(out, jsonrpc.NewError(nil, jsonrpc.InvalidRequest, "Invalid batch", nil))
+	}
+	responses := []*jsonrpc.Response{}
+	for _, item := range batch {
+		if resp := s.handleMessage(ctx, item); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(responses)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(encoded, '\n'))
+	return err
+}
+
+// writeMessage writes a JSON-RPC message followed by a newline
+func writeMessage(out io.Writer, msg jsonrpc.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(data, '\n'))
+	return err
+}
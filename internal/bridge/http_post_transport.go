@@ -5,11 +5,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"mime"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
 )
 
 // httpPostTransport implements a simple HTTP POST request/response transport
@@ -18,6 +25,14 @@ type httpPostTransport struct {
 	endpoint   string
 	httpClient *http.Client
 	debug      bool
+	redact     *redactor    // Masks secrets in debug logs
+	logger     *slog.Logger // Default: text on the standard log output
+
+	maxResponse int64 // Bytes read from a response before giving up; 0 means unlimited
+	truncate    bool  // Answer oversized tool calls with the text read so far
+
+	mu        sync.Mutex
+	sessionID string // Mcp-Session-Id assigned by streamable HTTP servers
 }
 
 func newHTTPPostTransport(endpoint string, client *http.Client, debug bool) *httpPostTransport {
@@ -33,106 +48,177 @@ func newHTTPPostTransport(endpoint string, client *http.Client, debug bool) *htt
 
 // Run starts the HTTP POST bridge loop
 func (t *httpPostTransport) Run(ctx context.Context) error {
+	t.log().Debug("HTTP POST bridge running, reading from stdin")
+	return serveStdio(ctx, os.Stdin, os.Stdout, t, t.log(), t.redact, 0)
+}
+
+func (t *httpPostTransport) log() *slog.Logger {
+	if t.logger == nil {
+		return fallbackLogger(t.debug)
+	}
+	return t.logger
+}
+
+// statusError reports a non-200 HTTP response from the remote server
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.code, e.status)
+}
+
+// Handle sends one request to the remote server via HTTP POST and returns its response
+func (t *httpPostTransport) Handle(ctx context.Context, msg *jsonrpc.Request) (*jsonrpc.Response, error) {
+	resp, err := t.post(ctx, msg)
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return jsonrpc.NewError(msg.ID, jsonrpc.InternalError, statusErr.Error(), nil), nil
+	}
+	return resp, err
+}
+
+// post sends msg and returns the server's response, or a *statusError when
+// the server answers with an unexpected HTTP status
+func (t *httpPostTransport) post(ctx context.Context, msg *jsonrpc.Request) (*jsonrpc.Response, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	logger := t.log().With("method", msg.Method, "id", msg.ID)
+	if sessionID != "" {
+		logger = logger.With("session", sessionID)
+	}
+
+	// Send to remote server via HTTP POST
 	if t.debug {
-		log.Printf("HTTP POST bridge running, reading from stdin...")
-	}
-
-	stdin := bufio.NewReader(os.Stdin)
-	stdout := os.Stdout
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			// Read from stdin
-			line, err := stdin.ReadBytes('\n')
-			if err != nil {
-				if err == io.EOF {
-					if t.debug {
-						log.Printf("EOF on stdin, shutting down")
-					}
-					return nil
-				}
-				return fmt.Errorf("read error: %w", err)
-			}
+		logger.Debug("Sending request", "direction", DirectionOut, "endpoint", t.redact.String(t.endpoint), messageAttr(t.redact.JSON(data)))
+	}
 
-			data := bytes.TrimSpace(line)
-			if len(data) == 0 {
-				continue
-			}
+	req, err := http.NewRequestWithContext(ctx, "POST", t.endpoint, bytes.NewReader(data))
+	if err != nil {
+		logger.Error("Failed to create request", "error", t.redact.String(err.Error()))
+		return nil, err
+	}
 
-			// Validate it's JSON
-			var msg map[string]interface{}
-			if err := json.Unmarshal(data, &msg); err != nil {
-				log.Printf("Invalid JSON received: %v", err)
-				continue
-			}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
 
-			// Send to remote server via HTTP POST
-			if t.debug {
-				log.Printf("→ Sending to %s: %s", t.endpoint, string(data))
-			}
+	start := time.Now()
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		logger.Warn("Request failed", "duration", time.Since(start), "error", t.redact.String(err.Error()))
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-			req, err := http.NewRequestWithContext(ctx, "POST", t.endpoint, bytes.NewReader(data))
-			if err != nil {
-				log.Printf("Failed to create request: %v", err)
-				continue
-			}
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+		logger = logger.With("session", sessionID)
+	}
 
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("Accept", "application/json")
-
-			resp, err := t.httpClient.Do(req)
-			if err != nil {
-				log.Printf("Request failed: %v", err)
-				// Create error response
-				errorResp := map[string]interface{}{
-					"jsonrpc": "2.0",
-					"id":      msg["id"],
-					"error": map[string]interface{}{
-						"code":    -32603,
-						"message": fmt.Sprintf("Bridge error: %v", err),
-					},
-				}
-				errorData, _ := json.Marshal(errorResp)
-				stdout.Write(errorData)
-				stdout.Write([]byte("\n"))
-				continue
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode != http.StatusOK {
-				log.Printf("HTTP %d: %s", resp.StatusCode, resp.Status)
-				// Create error response
-				errorResp := map[string]interface{}{
-					"jsonrpc": "2.0",
-					"id":      msg["id"],
-					"error": map[string]interface{}{
-						"code":    -32603,
-						"message": fmt.Sprintf("HTTP %d: %s", resp.StatusCode, resp.Status),
-					},
-				}
-				errorData, _ := json.Marshal(errorResp)
-				stdout.Write(errorData)
-				stdout.Write([]byte("\n"))
-				continue
-			}
+	// Notifications are acknowledged without a body; a request must get its
+	// response, or the client waits for it forever
+	if msg.ID == nil {
+		return nil, nil
+	}
+	if resp.StatusCode == http.StatusAccepted {
+		logger.Warn("Request accepted without a response", "duration", time.Since(start))
+		return jsonrpc.NewError(msg.ID, jsonrpc.InternalError, "Server accepted the request without answering it (HTTP 202)", nil), nil
+	}
 
-			// Read response
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				log.Printf("Failed to read response: %v", err)
-				continue
-			}
+	if resp.StatusCode != http.StatusOK {
+		logger.Warn("Unexpected HTTP status", "status", resp.StatusCode, "duration", time.Since(start))
+		return nil, &statusError{code: resp.StatusCode, status: resp.Status}
+	}
 
+	// Read response, stopping at the size limit
+	var src io.Reader = resp.Body
+	var partial bytes.Buffer
+	if t.maxResponse > 0 {
+		src = io.TeeReader(&sizeLimitReader{r: resp.Body, n: t.maxResponse}, &partial)
+	}
+	var body []byte
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+		body, err = readSSEResponse(src, func(event []byte) {
 			if t.debug {
-				log.Printf("← Received: %s", string(body))
+				logger.Debug("Skipping stream event", "direction", DirectionIn, messageAttr(t.redact.JSON(event)))
 			}
+		})
+	} else {
+		body, err = io.ReadAll(src)
+	}
+	if errors.Is(err, errResponseTooLarge) {
+		return t.oversized(msg, partial.Bytes()), nil
+	}
+	if err != nil {
+		logger.Warn("Failed to read response", "duration", time.Since(start), "error", t.redact.String(err.Error()))
+		return nil, err
+	}
 
-			// Write response to stdout
-			stdout.Write(body)
-			stdout.Write([]byte("\n"))
+	if t.debug {
+		logger.Debug("Received response", "direction", DirectionIn, "duration", time.Since(start), messageAttr(t.redact.JSON(body)))
+	}
+
+	parsed, err := jsonrpc.Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response from server: %w", err)
+	}
+	response, ok := parsed.(*jsonrpc.Response)
+	if !ok {
+		return nil, fmt.Errorf("expected a response from server, got %T", parsed)
+	}
+	return response, nil
+}
+
+// resetSession forgets the Mcp-Session-Id so the next initialize starts a
+// new session
+func (t *httpPostTransport) resetSession() {
+	t.mu.Lock()
+	t.sessionID = ""
+	t.mu.Unlock()
+}
+
+// readSSEResponse reads a streamable HTTP event stream until it carries a
+// JSON-RPC response, passing any server notifications sent before it to
+// skipped
+func readSSEResponse(r io.Reader, skipped func(event []byte)) ([]byte, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+			continue
+		}
+		if line != "" || len(data) == 0 {
+			continue
+		}
+
+		event := []byte(strings.Join(data, "\n"))
+		data = nil
+		if msg, err := jsonrpc.Parse(event); err == nil && msg.GetType() == jsonrpc.ResponseType {
+			return event, nil
 		}
+		skipped(event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		return []byte(strings.Join(data, "\n")), nil
 	}
+	return nil, io.ErrUnexpectedEOF
 }
@@ -0,0 +1,108 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestHTTPPostTransportForwardsNotifications(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	transport := newHTTPPostTransport(server.URL, nil, false)
+	responses := runStdio(t, transport, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	if len(responses) != 0 {
+		t.Errorf("Expected no answer to a notification, got %v", responses)
+	}
+	// Notifications built by the bridge itself, as failover and lazy do
+	if _, err := transport.Handle(context.Background(), &jsonrpc.Request{JSONRPC: jsonrpc.Version, Method: "notifications/initialized"}); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+
+	want := `{"jsonrpc":"2.0","method":"notifications/initialized"}`
+	if len(bodies) != 2 || bodies[0] != want || bodies[1] != want {
+		t.Errorf("Expected notifications to be forwarded without an id, got %q", bodies)
+	}
+}
+
+func TestServeStdioBatch(t *testing.T) {
+	h := HandlerFunc(func(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+		return newResult(req.ID, map[string]any{"method": req.Method})
+	})
+	in := strings.NewReader(strings.Join([]string{
+		`[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":2,"method":"tools/list"}]`,
+		`[{"jsonrpc":"2.0","method":"notifications/initialized"}]`,
+		`[]`,
+	}, "\n") + "\n")
+	var out bytes.Buffer
+	if err := serveStdio(context.Background(), in, &out, h, nil, nil, 0); err != nil {
+		t.Fatalf("serveStdio failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 answers, got %q", lines)
+	}
+	want := `[{"jsonrpc":"2.0","result":{"method":"ping"},"id":1},{"jsonrpc":"2.0","result":{"method":"tools/list"},"id":2}]`
+	if lines[0] != want {
+		t.Errorf("Expected %s, got %s", want, lines[0])
+	}
+	var invalid jsonrpc.Response
+	if err := json.Unmarshal([]byte(lines[1]), &invalid); err != nil || invalid.Error == nil || invalid.Error.Code != jsonrpc.InvalidRequest {
+		t.Errorf("Expected an invalid request error for an empty batch, got %s", lines[1])
+	}
+}
+
+func TestHTTPPostTransportStreamableHTTP(t *testing.T) {
+	var sessions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sessions = append(sessions, r.Header.Get("Mcp-Session-Id"))
+		msg, _ := jsonrpc.Parse(body)
+		switch msg.(*jsonrpc.Request).Method {
+		case "initialize":
+			w.Header().Set("Mcp-Session-Id", "abc")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+		case "tools/list":
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n" +
+				"data: {\"jsonrpc\":\"2.0\",\"id\":2,\n" +
+				"data: \"result\":{\"tools\":[]}}\n\n"))
+		default:
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+
+	responses := runStdio(t, newHTTPPostTransport(server.URL, nil, false),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+	)
+	if len(responses) != 3 {
+		t.Fatalf("Expected an answer to every request, got %v", responses)
+	}
+	if strings.Join(sessions, ",") != ",abc,abc" {
+		t.Errorf("Expected the assigned session id on later requests, got %q", sessions)
+	}
+	if result, _ := responses[1]["result"].(map[string]any); result == nil || result["tools"] == nil {
+		t.Errorf("Expected the response event to be read past the notification, got %v", responses[1])
+	}
+	if errObj, _ := responses[2]["error"].(map[string]any); errObj == nil || errObj["code"] != float64(jsonrpc.InternalError) || responses[2]["id"] != float64(3) {
+		t.Errorf("Expected a 202 to a request to be answered with an error, got %v", responses[2])
+	}
+}
@@ -36,6 +36,20 @@ type Request struct {
 	ID      interface{}     `json:"id"`
 }
 
+// MarshalJSON leaves out the id of a notification: under JSON-RPC 2.0 a
+// message with "id": null is a request
+func (r Request) MarshalJSON() ([]byte, error) {
+	type request Request
+	if r.ID != nil {
+		return json.Marshal(request(r))
+	}
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params,omitempty"`
+	}{r.JSONRPC, r.Method, r.Params})
+}
+
 func (r *Request) GetID() interface{}   { return r.ID }
 func (r *Request) GetVersion() string   { return r.JSONRPC }
 func (r *Request) GetType() MessageType { return RequestType }
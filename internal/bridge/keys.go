@@ -0,0 +1,116 @@
+package bridge
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultKeyCooldown is how long a rejected API key is skipped when the
+// server gives no Retry-After hint
+const defaultKeyCooldown = time.Minute
+
+// keyPool hands out API keys, rotating away from keys the server rejected
+type keyPool struct {
+	mu        sync.Mutex
+	keys      []string
+	current   int
+	coolUntil []time.Time
+	cooldown  time.Duration
+	logf      func(format string, v ...interface{})
+	now       func() time.Time
+}
+
+func newKeyPool(keys []string, cooldown time.Duration, logf func(string, ...interface{})) *keyPool {
+	if cooldown <= 0 {
+		cooldown = defaultKeyCooldown
+	}
+	return &keyPool{
+		keys:      keys,
+		coolUntil: make([]time.Time, len(keys)),
+		cooldown:  cooldown,
+		logf:      logf,
+		now:       time.Now,
+	}
+}
+
+// splitKeys parses a comma-separated list of API keys
+func splitKeys(s string) []string {
+	var keys []string
+	for _, key := range strings.Split(s, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// active returns the key to use next. If every key is cooling down, the one
+// that becomes available soonest is returned.
+func (p *keyPool) active() (int, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	soonest := p.current
+	for i := range p.keys {
+		idx := (p.current + i) % len(p.keys)
+		if !now.Before(p.coolUntil[idx]) {
+			p.switchTo(idx)
+			return idx, p.keys[idx]
+		}
+		if p.coolUntil[idx].Before(p.coolUntil[soonest]) {
+			soonest = idx
+		}
+	}
+	p.switchTo(soonest)
+	return soonest, p.keys[soonest]
+}
+
+// reject puts a key into cooldown after the server refused it
+func (p *keyPool) reject(idx, status int, retryAfter time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cooldown := p.cooldown
+	if retryAfter > 0 {
+		cooldown = retryAfter
+	}
+	p.coolUntil[idx] = p.now().Add(cooldown)
+	p.logf("API key %d/%d rejected (HTTP %d), cooling down for %s", idx+1, len(p.keys), status, cooldown)
+}
+
+func (p *keyPool) switchTo(idx int) {
+	if idx != p.current {
+		p.logf("Switching to API key %d/%d", idx+1, len(p.keys))
+		p.current = idx
+	}
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or HTTP-date form
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// retryWithNextKey decides whether a response should be retried with another key
+func retryWithNextKey(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// drain discards and closes a response body so the connection can be reused
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
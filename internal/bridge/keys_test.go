@@ -0,0 +1,122 @@
+package bridge
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyRotation(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	statuses := map[string]int{
+		"exhausted": http.StatusTooManyRequests,
+		"revoked":   http.StatusUnauthorized,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		seen = append(seen, key)
+		status, rejected := statuses[key]
+		mu.Unlock()
+
+		if rejected {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "30")
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	newClient := func(keys string) (*http.Client, *keyPool) {
+		b := New(srv.URL, keys, false)
+		client, err := b.httpClient("")
+		if err != nil {
+			t.Fatalf("Failed to build client: %v", err)
+		}
+		return client, client.Transport.(*addAuthTransport).keys
+	}
+	post := func(client *http.Client) (int, string) {
+		resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{"id":1}`))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	reset := func() {
+		mu.Lock()
+		seen = nil
+		mu.Unlock()
+	}
+
+	t.Run("rotates past rejected keys", func(t *testing.T) {
+		reset()
+		client, pool := newClient("exhausted, revoked, good")
+		now := time.Now()
+		pool.now = func() time.Time { return now }
+
+		code, body := post(client)
+		if code != http.StatusOK || body != `{"id":1}` {
+			t.Fatalf("Expected request to succeed with replayed body, got %d %q", code, body)
+		}
+		if got := strings.Join(seen, ","); got != "exhausted,revoked,good" {
+			t.Errorf("Unexpected key order: %s", got)
+		}
+
+		// Rejected keys stay in cooldown
+		reset()
+		post(client)
+		if got := strings.Join(seen, ","); got != "good" {
+			t.Errorf("Expected cooled-down keys to be skipped, got %s", got)
+		}
+
+		// The 429 key honours Retry-After; the 401 key uses the default cooldown
+		if got := pool.coolUntil[0].Sub(now); got != 30*time.Second {
+			t.Errorf("Expected Retry-After cooldown of 30s, got %s", got)
+		}
+		if got := pool.coolUntil[1].Sub(now); got != defaultKeyCooldown {
+			t.Errorf("Expected default cooldown, got %s", got)
+		}
+	})
+
+	t.Run("all keys rejected", func(t *testing.T) {
+		reset()
+		client, _ := newClient("exhausted,revoked")
+		if code, _ := post(client); code != http.StatusUnauthorized {
+			t.Errorf("Expected last rejection to be returned, got %d", code)
+		}
+		if len(seen) != 2 {
+			t.Errorf("Expected each key to be tried once, got %v", seen)
+		}
+	})
+
+	t.Run("key returns after cooldown", func(t *testing.T) {
+		pool := newKeyPool([]string{"a", "b"}, time.Minute, func(string, ...interface{}) {})
+		now := time.Now()
+		pool.now = func() time.Time { return now }
+
+		pool.reject(0, http.StatusTooManyRequests, 0)
+		if idx, _ := pool.active(); idx != 1 {
+			t.Fatalf("Expected second key while first cools down, got %d", idx)
+		}
+		pool.reject(1, http.StatusTooManyRequests, 2*time.Minute)
+		if idx, _ := pool.active(); idx != 0 {
+			t.Errorf("Expected soonest-available key when all cool down, got %d", idx)
+		}
+		now = now.Add(3 * time.Minute)
+		if idx, _ := pool.active(); idx != 0 {
+			t.Errorf("Expected first key to be available again, got %d", idx)
+		}
+	})
+}
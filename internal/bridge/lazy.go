@@ -0,0 +1,168 @@
+package bridge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// lazyConnect is a Handler that defers talking to the remote server until the
+// client sends a request that needs it. initialize is answered from the
+// result cached by the last successful session; the real initialize is sent
+// on first use and its result replaces the cache. Without a cached result
+// initialize is forwarded as usual.
+type lazyConnect struct {
+	next      Handler
+	cachePath string
+	logf      func(format string, v ...interface{})
+	logger    *slog.Logger // Warnings; default: slog.Default()
+
+	mu          sync.Mutex
+	connected   bool
+	initReq     *jsonrpc.Request // Client's initialize, sent on first use
+	initialized bool             // Client sent notifications/initialized
+	cached      json.RawMessage  // Result the client was given
+}
+
+func newLazyConnect(next Handler, cachePath string, logf func(string, ...interface{})) *lazyConnect {
+	return &lazyConnect{next: next, cachePath: cachePath, logf: logf}
+}
+
+// capabilityCachePath returns where the initialize result for remoteURL is
+// cached inside dir, defaulting to the user cache directory
+func capabilityCachePath(dir, remoteURL string) (string, error) {
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(cacheDir, "mcp-bridge")
+	}
+	sum := sha256.Sum256([]byte(remoteURL))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// Handle implements Handler
+func (l *lazyConnect) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.connected {
+		switch req.Method {
+		case "initialize":
+			if cached := l.loadCache(); cached != nil {
+				l.logf("Answering initialize from cached capabilities")
+				l.initReq, l.cached = req, cached
+				return &jsonrpc.Response{JSONRPC: jsonrpc.Version, ID: req.ID, Result: cached}, nil
+			}
+			resp, err := l.next.Handle(ctx, req)
+			if err == nil && resp != nil && resp.Error == nil {
+				l.connected = true
+				l.storeCache(resp.Result)
+			}
+			return resp, err
+		case "notifications/initialized":
+			l.initialized = true
+			return nil, nil
+		case "ping":
+			return newResult(req.ID, map[string]any{})
+		}
+
+		if req.ID == nil {
+			l.logf("Dropping %s sent before connecting", req.Method)
+			return nil, nil
+		}
+		if l.initReq != nil {
+			if err := l.connect(ctx); err != nil {
+				return nil, fmt.Errorf("failed to connect to remote MCP server: %w", err)
+			}
+		}
+	}
+	return l.next.Handle(ctx, req)
+}
+
+// connect initializes the remote session with the client's initialize request
+// and reconciles the live result with the one the client was given
+func (l *lazyConnect) connect(ctx context.Context) error {
+	l.logf("Connecting to remote MCP server")
+	resp, err := l.next.Handle(ctx, l.initReq)
+	if err != nil {
+		return err
+	}
+	if resp == nil || resp.Error != nil {
+		msg := "no response"
+		if resp != nil {
+			msg = resp.Error.Message
+		}
+		return fmt.Errorf("initialize failed: %s", msg)
+	}
+	l.connected = true
+
+	if !sameSession(l.cached, resp.Result) {
+		defaultLogger(l.logger).Warn("Remote server capabilities changed since they were cached; restart the client to pick them up")
+	}
+	l.storeCache(resp.Result)
+
+	if l.initialized {
+		if _, err := l.next.Handle(ctx, &jsonrpc.Request{JSONRPC: jsonrpc.Version, Method: "notifications/initialized"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *lazyConnect) loadCache() json.RawMessage {
+	data, err := os.ReadFile(l.cachePath)
+	if err != nil || !json.Valid(data) {
+		return nil
+	}
+	return data
+}
+
+// storeCache writes result atomically so a concurrent bridge never reads a
+// partial file
+func (l *lazyConnect) storeCache(result json.RawMessage) {
+	if err := os.MkdirAll(filepath.Dir(l.cachePath), 0o700); err != nil {
+		l.logf("Failed to cache capabilities: %v", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.cachePath), ".capabilities-*")
+	if err != nil {
+		l.logf("Failed to cache capabilities: %v", err)
+		return
+	}
+	_, err = tmp.Write(result)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), l.cachePath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		l.logf("Failed to cache capabilities: %v", err)
+	}
+}
+
+// sameSession reports whether two initialize results agree on what the
+// client relies on: protocol version, capabilities and instructions
+func sameSession(a, b json.RawMessage) bool {
+	var x, y struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		Capabilities    map[string]any `json:"capabilities"`
+		Instructions    string         `json:"instructions"`
+	}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,61 @@
+package bridge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLazyConnect(t *testing.T) {
+	cachePath, err := capabilityCachePath(t.TempDir(), "https://mcp.example.com")
+	if err != nil {
+		t.Fatalf("capabilityCachePath failed: %v", err)
+	}
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`
+
+	t.Run("first run forwards initialize and caches it", func(t *testing.T) {
+		remote := &fakeMCPServer{name: "remote"}
+		responses := runStdio(t, newLazyConnect(remote, cachePath, t.Logf), initialize)
+		if responses[0]["result"] == nil || len(remote.calls) != 1 {
+			t.Fatalf("Expected initialize to be forwarded, got %v and calls %v", responses, remote.calls)
+		}
+		if data, err := os.ReadFile(cachePath); err != nil || !strings.Contains(string(data), "Use remote tools.") {
+			t.Errorf("Expected initialize result to be cached, got %q (%v)", data, err)
+		}
+	})
+
+	t.Run("later runs answer from cache and connect on first use", func(t *testing.T) {
+		remote := &fakeMCPServer{name: "remote"}
+		l := newLazyConnect(remote, cachePath, t.Logf)
+		responses := runStdio(t, l,
+			initialize,
+			`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+			`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+		)
+		result := responses[0]["result"].(map[string]any)
+		if result["instructions"] != "Use remote tools." {
+			t.Errorf("Expected cached instructions, got %v", result)
+		}
+		if len(remote.calls) != 0 {
+			t.Fatalf("Expected no remote traffic before first use, got %v", remote.calls)
+		}
+
+		runStdio(t, l, `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`)
+		if got := strings.Join(remote.calls, ","); got != "initialize,notifications/initialized,tools/list" {
+			t.Errorf("Expected handshake before first request, got %s", got)
+		}
+	})
+
+	t.Run("reconcile refreshes the cache", func(t *testing.T) {
+		remote := &fakeMCPServer{name: "renamed"}
+		l := newLazyConnect(remote, cachePath, t.Logf)
+		runStdio(t, l, initialize, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+		if data, _ := os.ReadFile(cachePath); !strings.Contains(string(data), "Use renamed tools.") {
+			t.Errorf("Expected cache to hold the live result, got %s", data)
+		}
+		if entries, _ := os.ReadDir(filepath.Dir(cachePath)); len(entries) != 1 {
+			t.Errorf("Expected only the cache file to remain, got %d entries", len(entries))
+		}
+	})
+}
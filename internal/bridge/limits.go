@@ -0,0 +1,151 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// ShortenedMeta is the _meta key set to true on tool results the bridge cut
+// short, by truncation or offloading. They no longer carry the full output,
+// so output validation skips them.
+const ShortenedMeta = "mcp-bridge/shortened"
+
+// errResponseTooLarge stops reading a remote response at the size limit
+var errResponseTooLarge = errors.New("response exceeds the size limit")
+
+// readLine reads one newline-terminated message. A message longer than
+// limit bytes (0 means unlimited) is consumed keeping only its first limit
+// bytes and reported with tooLong.
+func readLine(r *bufio.Reader, limit int64) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !tooLong {
+			line = append(line, chunk...)
+			if limit > 0 && int64(len(bytes.TrimRight(line, "\r\n"))) > limit {
+				line, tooLong = line[:limit:limit], true
+			}
+		}
+		if err != bufio.ErrBufferFull {
+			return line, tooLong, err
+		}
+	}
+}
+
+// prefixID finds the id of a request from the start of its message, for
+// answering a message too large to parse. It returns nil when the id is
+// not among the members read before the message was cut.
+func prefixID(prefix []byte) interface{} {
+	dec := json.NewDecoder(bytes.NewReader(prefix))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil
+		}
+		if key == "id" {
+			var id interface{}
+			json.Unmarshal(value, &id)
+			return id
+		}
+	}
+	return nil
+}
+
+// sizeLimitReader reads at most n bytes from r and fails with
+// errResponseTooLarge if r has more
+type sizeLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, errResponseTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// oversized answers a request whose response went over the size limit.
+// With truncation a tool call gets the text read so far; otherwise, or when
+// no text could be recovered, the client gets an error.
+func (t *httpPostTransport) oversized(msg *jsonrpc.Request, partial []byte) *jsonrpc.Response {
+	text := fmt.Sprintf("Response from remote server exceeds the %d byte limit", t.maxResponse)
+	t.log().Warn(text, "method", msg.Method, "id", msg.ID)
+	if t.truncate && msg.Method == "tools/call" {
+		if salvaged := salvageText(partial); salvaged != "" {
+			resp, err := newResult(msg.ID, map[string]any{
+				"content": []any{map[string]any{
+					"type": "text",
+					"text": salvaged + "\n\n[Truncated: " + strings.ToLower(text[:1]) + text[1:] + "]",
+				}},
+				"_meta": map[string]any{ShortenedMeta: true},
+			})
+			if err == nil {
+				return resp
+			}
+		}
+	}
+	return jsonrpc.NewError(msg.ID, jsonrpc.InternalError, text, map[string]any{"limit": t.maxResponse})
+}
+
+var textField = regexp.MustCompile(`"text"\s*:\s*"`)
+
+// salvageText recovers the "text" strings of a JSON tool result that was cut
+// off, including the one the cut fell in
+func salvageText(partial []byte) string {
+	var parts []string
+	for _, loc := range textField.FindAllIndex(partial, -1) {
+		if text := decodePartialString(partial[loc[1]:]); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// decodePartialString decodes a JSON string body that starts at data and may
+// be missing its end
+func decodePartialString(data []byte) string {
+	end := len(data)
+	for i := 0; i < len(data); i++ {
+		if data[i] == '\\' {
+			i++
+		} else if data[i] == '"' {
+			end = i
+			break
+		}
+	}
+	raw := data[:end]
+	// A cut escape sequence is at most 6 bytes long (\uXXXX)
+	for trim := 0; trim <= 6 && trim <= len(raw); trim++ {
+		var s string
+		quoted := append(append([]byte{'"'}, raw[:len(raw)-trim]...), '"')
+		if json.Unmarshal(quoted, &s) == nil {
+			return s
+		}
+	}
+	return ""
+}
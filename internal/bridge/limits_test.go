@@ -0,0 +1,109 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestServeStdioRejectsOversizedRequests(t *testing.T) {
+	remote := &fakeMCPServer{name: "small"}
+	in := strings.NewReader(strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"blob":"` + strings.Repeat("x", 5000) + `"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo"}}`,
+		`{"jsonrpc":"2.0","method":"tools/call","params":{"name":"echo","arguments":{"blob":"` + strings.Repeat("x", 5000) + `"}},"id":3}`,
+	}, "\n") + "\n")
+	var out bytes.Buffer
+	if err := serveStdio(context.Background(), in, &out, remote, nil, nil, 1024); err != nil {
+		t.Fatalf("serveStdio failed: %v", err)
+	}
+
+	dec := json.NewDecoder(&out)
+	var rejected, handled, late map[string]any
+	for _, resp := range []*map[string]any{&rejected, &handled, &late} {
+		if err := dec.Decode(resp); err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+	}
+	errObj, _ := rejected["error"].(map[string]any)
+	if errObj == nil || errObj["code"] != float64(jsonrpc.InvalidRequest) || rejected["id"] != float64(1) {
+		t.Errorf("Expected InvalidRequest with the id of the request, got %v", rejected)
+	}
+	// The id comes after the part of the message kept
+	if errObj, _ := late["error"].(map[string]any); errObj == nil || late["id"] != nil {
+		t.Errorf("Expected InvalidRequest with a null id, got %v", late)
+	}
+	if handled["id"] != float64(2) || handled["result"] == nil {
+		t.Errorf("Expected the next request to be served, got %v", handled)
+	}
+	if len(remote.calls) != 1 {
+		t.Errorf("Expected only the small request upstream, got %v", remote.calls)
+	}
+}
+
+func TestResponseSizeLimit(t *testing.T) {
+	big := strings.Repeat("line \"quoted\" é\n", 10000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := map[string]any{"content": []any{map[string]any{"type": "text", "text": big}}}
+		data, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "result": result})
+		if r.URL.Query().Get("sse") != "" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	call := func(endpoint string, truncate bool) *jsonrpc.Response {
+		tr := newHTTPPostTransport(endpoint, nil, false)
+		tr.maxResponse, tr.truncate = 4096, truncate
+		resp, err := tr.Handle(context.Background(), &jsonrpc.Request{
+			JSONRPC: jsonrpc.Version, ID: 1, Method: "tools/call", Params: []byte(`{"name":"dump"}`),
+		})
+		if err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := call(server.URL, false)
+	if resp.Error == nil || !strings.Contains(resp.Error.Message, "4096 byte limit") {
+		t.Errorf("Expected a size limit error, got %+v", resp)
+	}
+
+	for _, endpoint := range []string{server.URL, server.URL + "?sse=1"} {
+		resp = call(endpoint, true)
+		var result struct {
+			Content []struct{ Text string } `json:"content"`
+		}
+		if resp.Error != nil || json.Unmarshal(resp.Result, &result) != nil || len(result.Content) != 1 {
+			t.Fatalf("Expected a truncated result from %s, got %+v", endpoint, resp)
+		}
+		text := result.Content[0].Text
+		if !strings.HasPrefix(text, "line \"quoted\" é\nline") || !strings.Contains(text, "[Truncated:") || len(text) > 4096 {
+			t.Errorf("Unexpected truncated text from %s (%d bytes): %.80q", endpoint, len(text), text)
+		}
+	}
+}
+
+func TestDecodePartialString(t *testing.T) {
+	for raw, want := range map[string]string{
+		`complete", "more": 1`: "complete",
+		`cut é here \u00`:      "cut é here ",
+		`ends with \`:          "ends with ",
+		`escaped \" quote`:     `escaped " quote`,
+	} {
+		if got := decodePartialString([]byte(raw)); got != want {
+			t.Errorf("decodePartialString(%s) = %q, want %q", raw, got, want)
+		}
+	}
+}
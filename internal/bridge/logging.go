@@ -0,0 +1,83 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// Log formats
+const (
+	LogFormatText = "text" // key=value lines (default)
+	LogFormatJSON = "json" // One JSON object per line
+)
+
+// Directions of logged messages
+const (
+	DirectionOut = "out" // From the client toward the remote server
+	DirectionIn  = "in"  // From the remote server toward the client
+)
+
+// NewLogger creates a structured logger writing to w in format at level
+// (debug, info, warn or error)
+func NewLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	lvl, err := ParseLogLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "", LogFormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q: use text or json", format)
+}
+
+// ParseLogLevel parses debug, info, warn or error
+func ParseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q: use debug, info, warn or error", level)
+}
+
+// fallbackLogger is used when no logger is configured: text through the
+// standard log output, with debug records only when debug is set
+func fallbackLogger(debug bool) *slog.Logger {
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+	return slog.New(slog.NewTextHandler(log.Writer(), &slog.HandlerOptions{Level: level}))
+}
+
+// defaultLogger returns l, or slog.Default() when l is nil
+func defaultLogger(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+// messageAttr carries a logged JSON-RPC message: embedded as JSON by the
+// JSON handler and as a single quoted line by the text handler
+func messageAttr(text string) slog.Attr {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(text)); err == nil {
+		return slog.Any("message", json.RawMessage(compact.Bytes()))
+	}
+	return slog.String("message", text)
+}
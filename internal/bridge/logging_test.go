@@ -0,0 +1,90 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestTransportStructuredLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Mcp-Session-Id", "session-1")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":7,"result":{"tools":[]}}`))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger, err := NewLogger(&logs, LogFormatJSON, "debug")
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	transport := newHTTPPostTransport(server.URL, nil, true)
+	transport.logger = logger
+	if _, err := transport.Handle(context.Background(), &jsonrpc.Request{JSONRPC: jsonrpc.Version, ID: 7, Method: "tools/list"}); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one record per direction, got:\n%s", logs.String())
+	}
+	var sent, received map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &sent); err != nil {
+		t.Fatalf("Expected JSON records, got %s", lines[0])
+	}
+	json.Unmarshal([]byte(lines[1]), &received)
+
+	if sent["direction"] != DirectionOut || sent["method"] != "tools/list" || sent["id"] != float64(7) {
+		t.Errorf("Unexpected request record %v", sent)
+	}
+	if message, _ := sent["message"].(map[string]any); message["method"] != "tools/list" {
+		t.Errorf("Expected the message embedded as JSON, got %v", sent["message"])
+	}
+	if received["direction"] != DirectionIn || received["session"] != "session-1" || received["duration"] == nil {
+		t.Errorf("Unexpected response record %v", received)
+	}
+}
+
+func TestServeStdioLogsThroughBridgeLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger, err := NewLogger(&logs, LogFormatJSON, "info")
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	b := New("https://mcp.example.com", "secret-key", false)
+	b.Name, b.Logger = "github", logger
+	redact, err := b.redactor()
+	if err != nil {
+		t.Fatalf("redactor failed: %v", err)
+	}
+
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":{"echo":"secret-key","token":"t0k3n"}}` + "\n")
+	if err := serveStdio(context.Background(), in, &bytes.Buffer{}, &fakeMCPServer{name: "github"}, b.logger(), redact, 0); err != nil {
+		t.Fatalf("serveStdio failed: %v", err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("Expected one JSON record, got %s", logs.String())
+	}
+	if record["server"] != "github" || record["msg"] != "Ignoring unexpected response from client" {
+		t.Errorf("Expected the warning with the server name, got %v", record)
+	}
+	if strings.Contains(logs.String(), "secret-key") || strings.Contains(logs.String(), "t0k3n") {
+		t.Errorf("Expected the client message to be redacted, got %s", logs.String())
+	}
+}
+
+func TestNewLoggerErrors(t *testing.T) {
+	if _, err := NewLogger(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+	if _, err := NewLogger(&bytes.Buffer{}, LogFormatText, "verbose"); err == nil {
+		t.Error("Expected an unknown level to be rejected")
+	}
+}
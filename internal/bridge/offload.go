@@ -0,0 +1,329 @@
+package bridge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// OffloadURIPrefix starts the URIs of tool results kept by the bridge
+const OffloadURIPrefix = "mcp-bridge://results/"
+
+// OffloadPageMeta is the _meta key under which resources/read reports the
+// page of an offloaded result: {"page": n, "pages": total, "next": uri}
+const OffloadPageMeta = "mcp-bridge/page"
+
+const (
+	maxOffloaded   = 32   // Results kept; the oldest is forgotten first
+	offloadPreview = 2048 // Most text bytes left in the truncated result
+)
+
+// offloadedResult is the full content of a tool result the client got
+// truncated
+type offloadedResult struct {
+	text     string
+	mimeType string
+	pages    []int // Start offset of each page
+}
+
+// offloadHandler keeps tool results larger than threshold bytes in the
+// bridge. The client gets a preview and a resource_link to a bridge-served
+// URI, which it reads page by page through resources/read. Structured
+// content stays in the preview when it fits under the threshold; results of
+// tools with an outputSchema whose structured content does not fit are
+// passed through, since clients validate them against the schema. When the
+// remote server has no resources, the bridge advertises the capability and
+// answers resource listings itself.
+type offloadHandler struct {
+	next      Handler
+	threshold int64
+	pageSize  int64
+	logf      func(format string, v ...interface{})
+
+	mu         sync.Mutex
+	results    map[string]*offloadedResult
+	order      []string        // IDs, oldest first
+	structured map[string]bool // Tools declaring an outputSchema, from tools/list
+	resources  bool            // The bridge added the resources capability
+}
+
+func newOffloadHandler(next Handler, threshold, pageSize int64, logf func(string, ...interface{})) Handler {
+	if threshold <= 0 {
+		return next
+	}
+	if pageSize <= 0 {
+		pageSize = threshold
+	}
+	return &offloadHandler{
+		next:       next,
+		threshold:  threshold,
+		pageSize:   pageSize,
+		logf:       logf,
+		results:    map[string]*offloadedResult{},
+		structured: map[string]bool{},
+	}
+}
+
+// Handle implements Handler
+func (h *offloadHandler) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	switch req.Method {
+	case "initialize":
+		// Clients only read offloaded results from servers with resources
+		resp, err := h.next.Handle(ctx, req)
+		editResult(resp, func(result map[string]any) {
+			caps, _ := result["capabilities"].(map[string]any)
+			if caps == nil {
+				caps = map[string]any{}
+				result["capabilities"] = caps
+			}
+			_, remote := caps["resources"]
+			if !remote {
+				caps["resources"] = map[string]any{}
+			}
+			h.mu.Lock()
+			h.resources = !remote
+			h.mu.Unlock()
+		})
+		return resp, err
+	case "resources/list", "resources/templates/list":
+		h.mu.Lock()
+		local := h.resources
+		h.mu.Unlock()
+		if local {
+			// Offloaded results are only reachable through their links
+			field := "resources"
+			if req.Method == "resources/templates/list" {
+				field = "resourceTemplates"
+			}
+			return newResult(req.ID, map[string]any{field: []any{}})
+		}
+	case "tools/list":
+		resp, err := h.next.Handle(ctx, req)
+		h.learn(req, resp)
+		return resp, err
+	case "resources/read":
+		params, err := decodeParams(req)
+		if err != nil {
+			return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, err.Error(), nil), nil
+		}
+		if uri, _ := params["uri"].(string); strings.HasPrefix(uri, OffloadURIPrefix) {
+			return h.read(req, uri)
+		}
+	case "tools/call":
+		resp, err := h.next.Handle(ctx, req)
+		if err != nil || resp == nil || resp.Error != nil || int64(len(resp.Result)) <= h.threshold {
+			return resp, err
+		}
+		params, _ := decodeParams(req)
+		name, _ := params["name"].(string)
+		return h.offload(name, resp), nil
+	}
+	return h.next.Handle(ctx, req)
+}
+
+// offload keeps the full result and replaces it with a preview and a link
+func (h *offloadHandler) offload(tool string, resp *jsonrpc.Response) *jsonrpc.Response {
+	var result map[string]any
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return resp
+	}
+	var structured json.RawMessage
+	if value, ok := result["structuredContent"]; ok {
+		structured, _ = json.Marshal(value)
+		h.mu.Lock()
+		declared := h.structured[tool]
+		h.mu.Unlock()
+		if int64(len(structured)) > h.threshold {
+			if declared {
+				h.logf("Not offloading the %d byte result of tool %s: its structured content exceeds the threshold", len(resp.Result), tool)
+				return resp
+			}
+			structured = nil
+		}
+	}
+	id, err := newResultID()
+	if err != nil {
+		h.logf("Not offloading the result of tool %s: %v", tool, err)
+		return resp
+	}
+	text, mimeType := resultText(result)
+	stored := &offloadedResult{text: text, mimeType: mimeType, pages: pageBreaks(text, h.pageSize)}
+	uri := OffloadURIPrefix + id
+	h.store(id, stored)
+	h.logf("Offloaded %d byte result of tool %s to %s", len(resp.Result), tool, uri)
+
+	note := fmt.Sprintf("[Truncated: the full %d-byte result is kept as %s in %d page(s); read page N with resources/read on %s?page=N]",
+		len(text), uri, len(stored.pages), uri)
+	if mimeType == "text/plain" {
+		// Only plain text makes a meaningful preview
+		size := min(offloadPreview, int(h.threshold/2), len(text))
+		note = text[:runeBoundary(text, size)] + "\n\n" + note
+	}
+	replaced := map[string]any{
+		"content": []any{
+			map[string]any{"type": "text", "text": note},
+			map[string]any{
+				"type":        "resource_link",
+				"uri":         uri,
+				"name":        tool + " result",
+				"mimeType":    mimeType,
+				"size":        len(text),
+				"description": fmt.Sprintf("Full result of tool %s", tool),
+			},
+		},
+	}
+	if isError, ok := result["isError"]; ok {
+		replaced["isError"] = isError
+	}
+	if structured != nil {
+		replaced["structuredContent"] = structured
+	}
+	replaced["_meta"] = map[string]any{ShortenedMeta: true}
+	out, err := newResult(resp.ID, replaced)
+	if err != nil {
+		return resp
+	}
+	return out
+}
+
+// learn records which tools declare an outputSchema from a page of
+// tools/list
+func (h *offloadHandler) learn(req *jsonrpc.Request, resp *jsonrpc.Response) {
+	if resp == nil || resp.Error != nil {
+		return
+	}
+	var result struct {
+		Tools []struct {
+			Name         string          `json:"name"`
+			OutputSchema json.RawMessage `json:"outputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return
+	}
+	params, _ := decodeParams(req)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, paged := params["cursor"]; !paged {
+		h.structured = map[string]bool{}
+	}
+	for _, tool := range result.Tools {
+		if len(tool.OutputSchema) > 0 {
+			h.structured[tool.Name] = true
+		}
+	}
+}
+
+// store keeps a result, forgetting the oldest beyond maxOffloaded
+func (h *offloadHandler) store(id string, result *offloadedResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.results[id] = result
+	h.order = append(h.order, id)
+	for len(h.order) > maxOffloaded {
+		delete(h.results, h.order[0])
+		h.order = h.order[1:]
+	}
+}
+
+// read answers resources/read for one page of an offloaded result. A URI
+// without a page query reads the first page.
+func (h *offloadHandler) read(req *jsonrpc.Request, uri string) (*jsonrpc.Response, error) {
+	id, query, _ := strings.Cut(strings.TrimPrefix(uri, OffloadURIPrefix), "?")
+	h.mu.Lock()
+	stored, ok := h.results[id]
+	h.mu.Unlock()
+	if !ok {
+		return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, "Unknown or expired result: "+uri, nil), nil
+	}
+
+	page := 1
+	if values, err := url.ParseQuery(query); err == nil && values.Get("page") != "" {
+		if page, err = strconv.Atoi(values.Get("page")); err != nil {
+			page = 0
+		}
+	}
+	pages := len(stored.pages)
+	if page < 1 || page > pages {
+		return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, fmt.Sprintf("Page out of range in %s: the result has %d page(s)", uri, pages), nil), nil
+	}
+	end := len(stored.text)
+	if page < pages {
+		end = stored.pages[page]
+	}
+
+	meta := map[string]any{"page": page, "pages": pages}
+	if page < pages {
+		meta["next"] = fmt.Sprintf("%s%s?page=%d", OffloadURIPrefix, id, page+1)
+	}
+	return newResult(req.ID, map[string]any{
+		"contents": []any{map[string]any{
+			"uri":      uri,
+			"mimeType": stored.mimeType,
+			"text":     stored.text[stored.pages[page-1]:end],
+		}},
+		"_meta": map[string]any{OffloadPageMeta: meta},
+	})
+}
+
+// resultText flattens a tool result for storage: the text of its content
+// when that is all it has, the whole result as JSON otherwise
+func resultText(result map[string]any) (string, string) {
+	content, _ := result["content"].([]any)
+	texts := []string{}
+	for _, item := range content {
+		m, _ := item.(map[string]any)
+		text, ok := m["text"].(string)
+		if m["type"] != "text" || !ok {
+			texts = nil
+			break
+		}
+		texts = append(texts, text)
+	}
+	if _, structured := result["structuredContent"]; texts != nil && !structured {
+		return strings.Join(texts, "\n"), "text/plain"
+	}
+	data, _ := json.MarshalIndent(result, "", "  ")
+	return string(data), "application/json"
+}
+
+// pageBreaks splits text into pages of at most size bytes without cutting
+// a UTF-8 sequence, returning the start offset of each page
+func pageBreaks(text string, size int64) []int {
+	starts := []int{0}
+	for start := 0; int64(len(text)-start) > size; {
+		end := runeBoundary(text, start+int(size))
+		if end <= start {
+			end = start + int(size)
+		}
+		starts = append(starts, end)
+		start = end
+	}
+	return starts
+}
+
+// runeBoundary moves i back to the start of the UTF-8 sequence it falls in
+func runeBoundary(text string, i int) int {
+	for i > 0 && i < len(text) && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return i
+}
+
+func newResultID() (string, error) {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,144 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestOffloadHandler(t *testing.T) {
+	// 250 bytes of text with a multi-byte rune straddling the first page break
+	big := strings.Repeat("a", 99) + "é" + strings.Repeat("b", 149)
+	remote := HandlerFunc(func(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+		params, _ := decodeParams(req)
+		if req.Method == "resources/read" {
+			return newResult(req.ID, map[string]any{"contents": []any{map[string]any{"uri": params["uri"], "text": "remote"}}})
+		}
+		text := "small"
+		if params["name"] == "dump" {
+			text = big
+		}
+		return newResult(req.ID, map[string]any{"content": []any{map[string]any{"type": "text", "text": text}}})
+	})
+	h := newOffloadHandler(remote, 200, 100, t.Logf)
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"dump"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"file:///a"}}`,
+	)
+	content := responses[0]["result"].(map[string]any)["content"].([]any)
+	if len(content) != 2 {
+		t.Fatalf("Expected a preview and a link, got %v", content)
+	}
+	preview := content[0].(map[string]any)["text"].(string)
+	link := content[1].(map[string]any)
+	uri, _ := link["uri"].(string)
+	if link["type"] != "resource_link" || !strings.HasPrefix(uri, OffloadURIPrefix) || link["size"] != float64(len(big)) {
+		t.Fatalf("Expected a resource_link to the kept result, got %v", link)
+	}
+	if !strings.HasPrefix(preview, strings.Repeat("a", 99)) || !strings.Contains(preview, "3 page(s)") {
+		t.Errorf("Expected a preview and paging note, got %q", preview)
+	}
+	if text := responses[1]["result"].(map[string]any)["content"].([]any)[0].(map[string]any)["text"]; text != "small" {
+		t.Errorf("Expected small results to pass unchanged, got %v", responses[1])
+	}
+	if text := responses[2]["result"].(map[string]any)["contents"].([]any)[0].(map[string]any)["text"]; text != "remote" {
+		t.Errorf("Expected other resources to be read from the remote, got %v", responses[2])
+	}
+
+	read := func(uri string) map[string]any {
+		t.Helper()
+		return runStdio(t, h, `{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"`+uri+`"}}`)[0]
+	}
+	var full strings.Builder
+	next := uri
+	for page := 1; next != ""; page++ {
+		result := read(next)["result"].(map[string]any)
+		full.WriteString(result["contents"].([]any)[0].(map[string]any)["text"].(string))
+		meta := result["_meta"].(map[string]any)[OffloadPageMeta].(map[string]any)
+		if meta["page"] != float64(page) || meta["pages"] != float64(3) {
+			t.Fatalf("Unexpected page metadata %v", meta)
+		}
+		next, _ = meta["next"].(string)
+	}
+	if full.String() != big {
+		t.Errorf("Expected the pages to add up to the full result, got %q", full.String())
+	}
+
+	for _, bad := range []string{uri + "?page=4", OffloadURIPrefix + "unknown"} {
+		if errObj, _ := read(bad)["error"].(map[string]any); errObj == nil || errObj["code"] != float64(jsonrpc.InvalidParams) {
+			t.Errorf("Expected %s to be rejected", bad)
+		}
+	}
+}
+
+func TestOffloadKeepsStructuredContent(t *testing.T) {
+	big := strings.Repeat("x", 300)
+	remote := HandlerFunc(func(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+		params, _ := decodeParams(req)
+		switch req.Method {
+		case "initialize":
+			return newResult(req.ID, map[string]any{"capabilities": map[string]any{"tools": map[string]any{}}})
+		case "tools/list":
+			return newResult(req.ID, map[string]any{"tools": []any{
+				map[string]any{"name": "report", "outputSchema": map[string]any{"type": "object"}},
+				map[string]any{"name": "export", "outputSchema": map[string]any{"type": "object"}},
+			}})
+		}
+		structured := map[string]any{"rows": 3}
+		if params["name"] == "export" {
+			structured = map[string]any{"data": big}
+		}
+		return newResult(req.ID, map[string]any{
+			"content":           []any{map[string]any{"type": "text", "text": big}},
+			"structuredContent": structured,
+		})
+	})
+	h := newOffloadHandler(remote, 200, 100, t.Logf)
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"report"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"export"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"resources/list"}`,
+	)
+	caps := responses[0]["result"].(map[string]any)["capabilities"].(map[string]any)
+	if _, ok := caps["resources"]; !ok {
+		t.Errorf("Expected the resources capability to be advertised, got %v", caps)
+	}
+	if resources, ok := responses[4]["result"].(map[string]any)["resources"].([]any); !ok || len(resources) != 0 {
+		t.Errorf("Expected the bridge to list no resources for a remote without them, got %v", responses[4])
+	}
+
+	report := responses[2]["result"].(map[string]any)
+	if len(report["content"].([]any)) != 2 || report["structuredContent"].(map[string]any)["rows"] != float64(3) {
+		t.Errorf("Expected an offloaded result that keeps its structured content, got %v", report)
+	}
+	export := responses[3]["result"].(map[string]any)
+	if export["structuredContent"].(map[string]any)["data"] != big {
+		t.Errorf("Expected a result with oversized structured content to pass unchanged, got %v", export)
+	}
+}
+
+func TestPageBreaks(t *testing.T) {
+	text := "ab日本cd"
+	breaks := pageBreaks(text, 4)
+	if got := fmt.Sprint(breaks); got != "[0 2 5 9]" {
+		t.Errorf("Expected pages to end before cut runes, got %s", got)
+	}
+	for i, start := range breaks {
+		end := len(text)
+		if i+1 < len(breaks) {
+			end = breaks[i+1]
+		}
+		if page := text[start:end]; !utf8.ValidString(page) || len(page) > 4 {
+			t.Errorf("Invalid page %q", page)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// ToolOverride changes how a remote tool is presented to the client. Empty
+// fields keep the remote value. Annotations and InputSchema are applied as
+// JSON merge patches (RFC 7386): objects merge recursively and null removes
+// a key.
+//
+// Defaults fill in arguments the client omits and are no longer required by
+// the advertised inputSchema. Forced arguments replace whatever the client
+// sent and are removed from the advertised inputSchema altogether.
+type ToolOverride struct {
+	Name        string // Exposed name; calls are translated back to the remote name
+	Title       string
+	Description string
+	Annotations map[string]any
+	InputSchema map[string]any
+	Defaults    map[string]any
+	Forced      map[string]any
+}
+
+// injects reports whether the override changes call arguments
+func (o ToolOverride) injects() bool {
+	return len(o.Defaults) > 0 || len(o.Forced) > 0
+}
+
+// inject applies Defaults and Forced to the arguments of a call
+func (o ToolOverride) inject(arguments any) map[string]any {
+	args := map[string]any{}
+	if given, ok := arguments.(map[string]any); ok {
+		for key, value := range given {
+			args[key] = value
+		}
+	}
+	for key, value := range o.Defaults {
+		if _, ok := args[key]; !ok {
+			args[key] = value
+		}
+	}
+	for key, value := range o.Forced {
+		args[key] = value
+	}
+	return args
+}
+
+// overrideHandler applies ToolOverrides to tools/list results and translates
+// renamed tools in tools/call back to their remote names
+type overrideHandler struct {
+	next      Handler
+	overrides map[string]ToolOverride // By remote name
+	remote    map[string]string       // Exposed name to remote name
+}
+
+func newOverrideHandler(next Handler, overrides map[string]ToolOverride) (Handler, error) {
+	if len(overrides) == 0 {
+		return next, nil
+	}
+	h := &overrideHandler{next: next, overrides: overrides, remote: map[string]string{}}
+	for original, o := range overrides {
+		if o.Name == "" || o.Name == original {
+			continue
+		}
+		if prev, ok := h.remote[o.Name]; ok {
+			return nil, fmt.Errorf("tools %s and %s are both renamed to %s", prev, original, o.Name)
+		}
+		h.remote[o.Name] = original
+	}
+	return h, nil
+}
+
+// Handle implements Handler
+func (h *overrideHandler) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	switch req.Method {
+	case "tools/list":
+		resp, err := h.next.Handle(ctx, req)
+		editResult(resp, func(result map[string]any) {
+			tools, _ := result["tools"].([]any)
+			for _, item := range tools {
+				if tool, ok := item.(map[string]any); ok {
+					h.apply(tool)
+				}
+			}
+		})
+		return resp, err
+
+	case "tools/call":
+		params, err := decodeParams(req)
+		if err != nil {
+			return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, err.Error(), nil), nil
+		}
+		name, _ := params["name"].(string)
+		remote := name
+		if original, ok := h.remote[name]; ok {
+			remote = original
+		} else if o, ok := h.overrides[name]; ok && o.Name != "" && o.Name != name {
+			return jsonrpc.NewError(req.ID, jsonrpc.MethodNotFound, "Unknown tool: "+name, nil), nil
+		}
+		o := h.overrides[remote]
+		if remote == name && !o.injects() {
+			break
+		}
+		params["name"] = remote
+		if o.injects() {
+			params["arguments"] = o.inject(params["arguments"])
+		}
+		return h.next.Handle(ctx, withParams(req, params))
+	}
+	return h.next.Handle(ctx, req)
+}
+
+// apply rewrites one tool definition in place
+func (h *overrideHandler) apply(tool map[string]any) {
+	name, _ := tool["name"].(string)
+	o, ok := h.overrides[name]
+	if !ok {
+		return
+	}
+	if o.Name != "" {
+		tool["name"] = o.Name
+	}
+	if o.Title != "" {
+		tool["title"] = o.Title
+	}
+	if o.Description != "" {
+		tool["description"] = o.Description
+	}
+	if o.Annotations != nil {
+		tool["annotations"] = mergePatch(tool["annotations"], o.Annotations)
+	}
+	if o.InputSchema != nil {
+		tool["inputSchema"] = mergePatch(tool["inputSchema"], o.InputSchema)
+	}
+	if schema, ok := tool["inputSchema"].(map[string]any); ok && o.injects() {
+		hideArguments(schema, o)
+	}
+}
+
+// hideArguments removes forced arguments from an input schema and drops
+// defaulted ones from its required list
+func hideArguments(schema map[string]any, o ToolOverride) {
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		for key := range o.Forced {
+			delete(properties, key)
+		}
+	}
+	required, ok := schema["required"].([]any)
+	if !ok {
+		return
+	}
+	kept := []any{}
+	for _, item := range required {
+		key, _ := item.(string)
+		_, forced := o.Forced[key]
+		_, defaulted := o.Defaults[key]
+		if !forced && !defaulted {
+			kept = append(kept, item)
+		}
+	}
+	schema["required"] = kept
+}
+
+// mergePatch applies an RFC 7386 JSON merge patch to target
+func mergePatch(target any, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = mergePatch(t[key], value)
+		}
+	}
+	return t
+}
@@ -0,0 +1,162 @@
+package bridge
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestOverrideHandler(t *testing.T) {
+	remote := &fakeMCPServer{
+		name: "remote",
+		tools: []map[string]any{
+			{
+				"name":        "edit",
+				"description": "Edits things",
+				"annotations": map[string]any{"readOnlyHint": true, "title": "Edit"},
+				"inputSchema": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"path":  map[string]any{"type": "string"},
+						"force": map[string]any{"type": "boolean"},
+					},
+				},
+			},
+			{"name": "search"},
+		},
+	}
+	h, err := newOverrideHandler(remote, map[string]ToolOverride{
+		"edit": {
+			Name:        "remote_edit",
+			Description: "Edits a file on the remote host",
+			Annotations: map[string]any{"readOnlyHint": false, "title": nil},
+			InputSchema: map[string]any{"properties": map[string]any{
+				"path":  map[string]any{"description": "Path relative to the repository root"},
+				"force": nil,
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newOverrideHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"remote_edit","arguments":{"path":"a.go"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"edit"}}`,
+	)
+
+	tools := responses[0]["result"].(map[string]any)["tools"].([]any)
+	edit := tools[0].(map[string]any)
+	if edit["name"] != "remote_edit" || edit["description"] != "Edits a file on the remote host" {
+		t.Errorf("Expected renamed tool with new description, got %v", edit)
+	}
+	if annotations := edit["annotations"].(map[string]any); annotations["readOnlyHint"] != false || annotations["title"] != nil {
+		t.Errorf("Expected annotations to be merge-patched, got %v", annotations)
+	}
+	props := edit["inputSchema"].(map[string]any)["properties"].(map[string]any)
+	path := props["path"].(map[string]any)
+	if path["type"] != "string" || path["description"] != "Path relative to the repository root" || props["force"] != nil {
+		t.Errorf("Expected schema to be merge-patched, got %v", props)
+	}
+	if tools[1].(map[string]any)["name"] != "search" {
+		t.Errorf("Expected other tools to be untouched, got %v", tools[1])
+	}
+
+	if last := remote.calls[1]; last != "tools/call edit" {
+		t.Errorf("Expected call to be translated to the remote name, got %q", last)
+	}
+	if errObj, ok := responses[2]["error"].(map[string]any); !ok || errObj["code"] != float64(jsonrpc.MethodNotFound) {
+		t.Errorf("Expected the remote name to be hidden once renamed, got %v", responses[2])
+	}
+}
+
+func TestOverrideHandlerRejectsDuplicateNames(t *testing.T) {
+	_, err := newOverrideHandler(&fakeMCPServer{}, map[string]ToolOverride{
+		"a": {Name: "same"},
+		"b": {Name: "same"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "same") {
+		t.Errorf("Expected error for two tools renamed to the same name, got %v", err)
+	}
+}
+
+func TestOverrideHandlerInjectsArguments(t *testing.T) {
+	remote := &fakeMCPServer{
+		name: "remote",
+		tools: []map[string]any{{
+			"name": "query",
+			"inputSchema": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"sql":      map[string]any{"type": "string"},
+					"limit":    map[string]any{"type": "integer"},
+					"database": map[string]any{"type": "string"},
+				},
+				"required": []any{"sql", "limit", "database"},
+			},
+		}},
+	}
+	h, err := newOverrideHandler(remote, map[string]ToolOverride{
+		"query": {
+			Defaults: map[string]any{"limit": 100},
+			Forced:   map[string]any{"database": "analytics"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newOverrideHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"query","arguments":{"sql":"select 1","database":"prod"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"query","arguments":{"sql":"select 1","limit":5}}}`,
+	)
+
+	schema := responses[0]["result"].(map[string]any)["tools"].([]any)[0].(map[string]any)["inputSchema"].(map[string]any)
+	if _, ok := schema["properties"].(map[string]any)["database"]; ok {
+		t.Errorf("Expected forced argument to be hidden, got %v", schema["properties"])
+	}
+	if required := schema["required"].([]any); len(required) != 1 || required[0] != "sql" {
+		t.Errorf("Expected only sql to stay required, got %v", required)
+	}
+
+	text := func(resp map[string]any) string {
+		content := resp["result"].(map[string]any)["content"].([]any)
+		return content[0].(map[string]any)["text"].(string)
+	}
+	if got := text(responses[1]); !strings.Contains(got, "database:analytics") || !strings.Contains(got, "limit:100") {
+		t.Errorf("Expected default filled in and forced value applied, got %q", got)
+	}
+	if got := text(responses[2]); !strings.Contains(got, "database:analytics") || !strings.Contains(got, "limit:5") {
+		t.Errorf("Expected client value to win over the default, got %q", got)
+	}
+}
+
+func TestToolSettingsMatchExposedNames(t *testing.T) {
+	remote := &fakeMCPServer{name: "remote", tools: []map[string]any{{"name": "edit"}, {"name": "search"}}}
+	server := httptest.NewServer(remote)
+	defer server.Close()
+
+	b := New(server.URL, "", false)
+	b.ToolOverrides = map[string]ToolOverride{"edit": {Name: "remote_edit"}}
+	b.ToolFilter = Filter{Allow: []string{"remote_*"}}
+	h, err := b.postHandler()
+	if err != nil {
+		t.Fatalf("postHandler failed: %v", err)
+	}
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"remote_edit"}}`,
+	)
+
+	tools := responses[0]["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "remote_edit" {
+		t.Errorf("Expected the filter to match the exposed name, got %v", tools)
+	}
+	if responses[1]["result"] == nil {
+		t.Errorf("Expected the renamed tool to be callable, got %v", responses[1])
+	}
+}
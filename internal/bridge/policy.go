@@ -0,0 +1,222 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// Policy rule actions
+const (
+	PolicyAllow     = "allow"      // Let the request through, skipping later rules
+	PolicyDeny      = "deny"       // Reject the request
+	PolicyRateLimit = "rate_limit" // Reject the request once Limit is used up
+)
+
+// PolicyRule is one rule of a bridge policy. Rules are evaluated in order
+// against every client request; the first allow or deny that matches
+// decides, and every matching rate limit must have room for the request.
+// Requests no rule denies are forwarded.
+type PolicyRule struct {
+	Name   string // Shown in errors and logs; defaults to the rule's position
+	When   string // Expression selecting requests; empty matches all
+	Action string // PolicyAllow, PolicyDeny or PolicyRateLimit
+	Reason string // Explanation added to deny errors
+	Limit  string // Rate as count/period, e.g. "10/min" or "100/1h"
+}
+
+// policyRule is a compiled PolicyRule. Rate limits use the same token
+// buckets as RateLimits: count tokens that refill evenly over the period.
+type policyRule struct {
+	label  string
+	action string
+	reason string
+	when   expr
+	bucket *tokenBucket // For PolicyRateLimit
+}
+
+// policyHandler evaluates policy rules before forwarding requests
+type policyHandler struct {
+	next   Handler
+	rules  []*policyRule
+	server string
+	logf   func(format string, v ...interface{})
+	now    func() time.Time
+
+	mu     sync.Mutex
+	client map[string]any // clientInfo from initialize
+}
+
+func newPolicyHandler(next Handler, rules []PolicyRule, server string, logf func(string, ...interface{})) (Handler, error) {
+	if len(rules) == 0 {
+		return next, nil
+	}
+	h := &policyHandler{next: next, server: server, logf: logf, now: time.Now, client: map[string]any{}}
+	for i, rule := range rules {
+		compiled, err := compilePolicyRule(rule)
+		if err != nil {
+			label := rule.Name
+			if label == "" {
+				label = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("policy rule %s: %w", label, err)
+		}
+		if compiled.label == "" {
+			compiled.label = fmt.Sprintf("#%d", i+1)
+		}
+		h.rules = append(h.rules, compiled)
+	}
+	return h, nil
+}
+
+func compilePolicyRule(rule PolicyRule) (*policyRule, error) {
+	r := &policyRule{label: rule.Name, action: rule.Action, reason: rule.Reason}
+	if strings.TrimSpace(rule.When) != "" {
+		when, err := parseExpr(rule.When)
+		if err != nil {
+			return nil, err
+		}
+		r.when = when
+	}
+	switch rule.Action {
+	case PolicyAllow, PolicyDeny:
+		if rule.Limit != "" {
+			return nil, fmt.Errorf("limit only applies to %s rules", PolicyRateLimit)
+		}
+	case PolicyRateLimit:
+		count, window, err := parseRate(rule.Limit)
+		if err != nil {
+			return nil, err
+		}
+		r.bucket = bucketFor(rule.Name, count, window, 0)
+	default:
+		return nil, fmt.Errorf("unknown action %q (want %s, %s or %s)", rule.Action, PolicyAllow, PolicyDeny, PolicyRateLimit)
+	}
+	return r, nil
+}
+
+// parseRate parses count/period, where period is a unit (s, sec, second,
+// m, min, minute, h, hour, d, day) or a duration such as 30s
+func parseRate(s string) (int, time.Duration, error) {
+	count, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid rate %q (want count/period, e.g. 10/min)", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate %q: count must be a positive integer", s)
+	}
+	period = strings.TrimSpace(period)
+	units := map[string]time.Duration{
+		"s": time.Second, "sec": time.Second, "second": time.Second,
+		"m": time.Minute, "min": time.Minute, "minute": time.Minute,
+		"h": time.Hour, "hour": time.Hour,
+		"d": 24 * time.Hour, "day": 24 * time.Hour,
+	}
+	window, ok := units[period]
+	if !ok {
+		if window, err = time.ParseDuration(period); err != nil || window <= 0 {
+			return 0, 0, fmt.Errorf("invalid rate %q: unknown period %q", s, period)
+		}
+	}
+	return n, window, nil
+}
+
+// Handle implements Handler
+func (h *policyHandler) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	params, _ := decodeParams(req)
+	if params == nil {
+		params = map[string]any{}
+	}
+
+	h.mu.Lock()
+	if req.Method == "initialize" {
+		if info, ok := params["clientInfo"].(map[string]any); ok {
+			h.client = info
+		}
+	}
+	rule, retryAfter := h.evaluate(h.env(req, params))
+	h.mu.Unlock()
+
+	if rule == nil {
+		return h.next.Handle(ctx, req)
+	}
+	if rule.action == PolicyRateLimit {
+		h.logf("Policy rule %s rate limited %s", rule.label, req.Method)
+		if req.ID == nil {
+			return nil, nil
+		}
+		seconds := math.Ceil(retryAfter.Seconds())
+		return jsonrpc.NewError(req.ID, jsonrpc.InvalidRequest,
+			fmt.Sprintf("Rate limit exceeded by policy rule %s; retry in %.0fs", rule.label, seconds),
+			map[string]any{"rule": rule.label, "retryAfter": seconds}), nil
+	}
+
+	h.logf("Policy rule %s denied %s", rule.label, req.Method)
+	if req.ID == nil {
+		return nil, nil
+	}
+	msg := "Request denied by policy rule " + rule.label
+	if rule.reason != "" {
+		msg += ": " + rule.reason
+	}
+	return jsonrpc.NewError(req.ID, jsonrpc.InvalidRequest, msg, map[string]any{"rule": rule.label}), nil
+}
+
+// evaluate returns the rule that rejects the request, with how long to wait
+// for a rate limit, or nil to forward it. Rate limits are only charged when
+// the request is forwarded. Called with h.mu held.
+func (h *policyHandler) evaluate(env map[string]any) (*policyRule, time.Duration) {
+	now := h.now()
+	var limits []*policyRule
+	for _, rule := range h.rules {
+		if rule.when != nil && !truthy(rule.when.eval(env)) {
+			continue
+		}
+		if rule.action == PolicyDeny {
+			return rule, 0
+		}
+		if rule.action == PolicyAllow {
+			break
+		}
+		limits = append(limits, rule)
+	}
+	for _, rule := range limits {
+		if wait := rule.bucket.wait(now); wait > 0 {
+			return rule, wait
+		}
+	}
+	for _, rule := range limits {
+		rule.bucket.tokens--
+	}
+	return nil, 0
+}
+
+// env returns the fields rule expressions can refer to
+func (h *policyHandler) env(req *jsonrpc.Request, params map[string]any) map[string]any {
+	now := h.now()
+	env := map[string]any{
+		"method":    req.Method,
+		"params":    params,
+		"name":      params["name"],
+		"uri":       params["uri"],
+		"arguments": params["arguments"],
+		"client":    h.client,
+		"server":    h.server,
+		"time": map[string]any{
+			"hour":    float64(now.Hour()),
+			"minute":  float64(now.Minute()),
+			"weekday": now.Weekday().String(),
+		},
+	}
+	if req.Method == "tools/call" {
+		env["tool"] = params["name"]
+	}
+	return env
+}
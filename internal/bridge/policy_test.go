@@ -0,0 +1,119 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestPolicyHandler(t *testing.T) {
+	remote := &fakeMCPServer{name: "db"}
+	h, err := newPolicyHandler(remote, []PolicyRule{
+		{Name: "trusted", When: `client.name == "ci"`, Action: PolicyAllow},
+		{Name: "no-drop", When: `tool matches "db_*" and arguments.query =~ "(?i)\bdrop\b"`, Action: PolicyDeny, Reason: "destructive SQL"},
+		{Name: "search", When: `tool == "search"`, Action: PolicyRateLimit, Limit: "2/min"},
+	}, "db", func(string, ...interface{}) {})
+	if err != nil {
+		t.Fatalf("newPolicyHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"cursor"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"db_query","arguments":{"query":"drop table users"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"db_query","arguments":{"query":"select 1"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"search"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"search"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"search"}}`,
+	)
+
+	errObj, _ := responses[1]["error"].(map[string]any)
+	if errObj == nil || errObj["code"] != float64(jsonrpc.InvalidRequest) ||
+		!strings.Contains(errObj["message"].(string), "no-drop: destructive SQL") {
+		t.Errorf("Expected DROP to be denied, got %v", responses[1])
+	}
+	for _, i := range []int{2, 3, 4} {
+		if responses[i]["result"] == nil {
+			t.Errorf("Expected request %d to be forwarded, got %v", i+1, responses[i])
+		}
+	}
+	errObj, _ = responses[5]["error"].(map[string]any)
+	if errObj == nil {
+		t.Fatalf("Expected third search to be rate limited, got %v", responses[5])
+	}
+	data := errObj["data"].(map[string]any)
+	if data["rule"] != "search" || data["retryAfter"].(float64) <= 0 {
+		t.Errorf("Expected retry-after data, got %v", data)
+	}
+	if strings.Join(remote.calls, ",") != "initialize,tools/call db_query,tools/call search,tools/call search" {
+		t.Errorf("Unexpected upstream calls: %v", remote.calls)
+	}
+}
+
+func TestPolicyAllowSkipsLaterRules(t *testing.T) {
+	remote := &fakeMCPServer{name: "db"}
+	h, err := newPolicyHandler(remote, []PolicyRule{
+		{When: `client.name == "ci"`, Action: PolicyAllow},
+		{Action: PolicyDeny},
+	}, "db", func(string, ...interface{}) {})
+	if err != nil {
+		t.Fatalf("newPolicyHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"ci"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"db_query"}}`,
+	)
+	if responses[1]["result"] == nil {
+		t.Errorf("Expected the allow rule to win, got %v", responses[1])
+	}
+}
+
+func TestPolicyRateLimitWindow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	remote := &fakeMCPServer{name: "db"}
+	handler, err := newPolicyHandler(remote, []PolicyRule{
+		{Action: PolicyRateLimit, Limit: "1/30s"},
+	}, "db", func(string, ...interface{}) {})
+	if err != nil {
+		t.Fatalf("newPolicyHandler failed: %v", err)
+	}
+	h := handler.(*policyHandler)
+	h.now = func() time.Time { return now }
+
+	call := func() *jsonrpc.Response {
+		resp, err := h.Handle(context.Background(), &jsonrpc.Request{JSONRPC: jsonrpc.Version, ID: 1, Method: "tools/call", Params: []byte(`{"name":"search"}`)})
+		if err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+		return resp
+	}
+	if resp := call(); resp.Error != nil {
+		t.Fatalf("Expected first request through, got %v", resp.Error)
+	}
+	now = now.Add(20 * time.Second)
+	if resp := call(); resp.Error == nil || !strings.Contains(string(resp.Error.Data), `"retryAfter":10`) {
+		t.Errorf("Expected a 10s retry-after, got %+v", resp.Error)
+	}
+	now = now.Add(10 * time.Second)
+	if resp := call(); resp.Error != nil {
+		t.Errorf("Expected the window to have passed, got %v", resp.Error)
+	}
+}
+
+func TestPolicyRuleErrors(t *testing.T) {
+	for _, rule := range []PolicyRule{
+		{Action: "block"},
+		{Action: PolicyDeny, When: `tool ==`},
+		{Action: PolicyRateLimit},
+		{Action: PolicyRateLimit, Limit: "ten/min"},
+		{Action: PolicyRateLimit, Limit: "10/fortnight"},
+		{Action: PolicyDeny, Limit: "1/s"},
+	} {
+		if _, err := newPolicyHandler(&fakeMCPServer{}, []PolicyRule{rule}, "", nil); err == nil {
+			t.Errorf("Expected %+v to be rejected", rule)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package bridge
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyOptions configures an explicit outbound proxy for remote connections.
+// When URL is empty the standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables apply instead.
+type ProxyOptions struct {
+	URL      string // http://, https://, socks5:// or socks5h:// proxy URL, or "direct"
+	Username string // Proxy credentials; override any userinfo in URL
+	Password string
+	NoProxy  string // Comma-separated hosts, domains and CIDRs that bypass the proxy
+}
+
+// ProxyFunc returns the proxy selection function for an http.Transport
+func (o ProxyOptions) ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	switch o.URL {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case "direct":
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", proxyURL.Scheme)
+	}
+	if o.Username != "" {
+		proxyURL.User = url.UserPassword(o.Username, o.Password)
+	}
+
+	// httpproxy gives us NO_PROXY matching without reading the environment
+	cfg := &httpproxy.Config{
+		HTTPProxy:  proxyURL.String(),
+		HTTPSProxy: proxyURL.String(),
+		NoProxy:    o.NoProxy,
+	}
+	proxyForURL := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyForURL(req.URL)
+	}, nil
+}
@@ -0,0 +1,84 @@
+package bridge
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyOptions(t *testing.T) {
+	t.Run("authenticated HTTP proxy", func(t *testing.T) {
+		var gotURL, gotAuth string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotURL = r.URL.String()
+			gotAuth = r.Header.Get("Proxy-Authorization")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer proxy.Close()
+
+		b := New("http://mcp.example.test", "", false)
+		b.Proxy = ProxyOptions{URL: proxy.URL, Username: "dev", Password: "s3cret"}
+		client, err := b.httpClient("")
+		if err != nil {
+			t.Fatalf("Failed to build client: %v", err)
+		}
+
+		resp, err := client.Get("http://mcp.example.test/mcp")
+		if err != nil {
+			t.Fatalf("Request through proxy failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if gotURL != "http://mcp.example.test/mcp" {
+			t.Errorf("Expected proxied request for remote URL, got %q", gotURL)
+		}
+		want := "Basic " + base64.StdEncoding.EncodeToString([]byte("dev:s3cret"))
+		if gotAuth != want {
+			t.Errorf("Expected Proxy-Authorization %q, got %q", want, gotAuth)
+		}
+	})
+
+	t.Run("proxy selection", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			opts   ProxyOptions
+			target string
+			want   string
+		}{
+			{"socks5 with credentials", ProxyOptions{URL: "socks5://proxy.corp:1080", Username: "u", Password: "p"}, "https://mcp.example.com", "socks5://u:p@proxy.corp:1080"},
+			{"no-proxy domain", ProxyOptions{URL: "http://proxy.corp:3128", NoProxy: ".internal.example"}, "https://mcp.internal.example", ""},
+			{"no-proxy CIDR", ProxyOptions{URL: "http://proxy.corp:3128", NoProxy: "10.0.0.0/8"}, "http://10.1.2.3/mcp", ""},
+			{"not excluded", ProxyOptions{URL: "http://proxy.corp:3128", NoProxy: ".internal.example"}, "https://mcp.example.com", "http://proxy.corp:3128"},
+			{"direct", ProxyOptions{URL: "direct"}, "https://mcp.example.com", ""},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				proxyFunc, err := tt.opts.ProxyFunc()
+				if err != nil {
+					t.Fatalf("ProxyFunc failed: %v", err)
+				}
+				got := ""
+				if proxyFunc != nil {
+					req, _ := http.NewRequest("GET", tt.target, nil)
+					u, err := proxyFunc(req)
+					if err != nil {
+						t.Fatalf("Proxy lookup failed: %v", err)
+					}
+					if u != nil {
+						got = u.String()
+					}
+				}
+				if got != tt.want {
+					t.Errorf("Expected proxy %q, got %q", tt.want, got)
+				}
+			})
+		}
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		if _, err := (ProxyOptions{URL: "ftp://proxy.corp"}).ProxyFunc(); err == nil {
+			t.Error("Expected error for unsupported proxy scheme")
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// RateLimit is a token bucket that refills evenly at Rate and holds up to
+// Burst tokens
+type RateLimit struct {
+	Rate  string // count/period, e.g. "10/min"; see PolicyRule.Limit
+	Burst int    // Default: the rate's count
+}
+
+// RateLimits throttles client requests. A request takes a token from the
+// global bucket, from its method's bucket and, for tools/call, from its
+// tool's bucket. When a bucket is empty the request waits for a token if
+// that takes no longer than MaxWait, and is rejected otherwise.
+type RateLimits struct {
+	Global  RateLimit
+	Methods map[string]RateLimit // By JSON-RPC method
+	Tools   map[string]RateLimit // By tool name as the client sees it
+	MaxWait time.Duration        // Default 0: reject at once
+}
+
+// IsZero reports whether no limit is configured
+func (r RateLimits) IsZero() bool {
+	return r.Global.Rate == "" && len(r.Methods) == 0 && len(r.Tools) == 0
+}
+
+// tokenBucket is a compiled RateLimit. Tokens go negative while requests
+// wait for them.
+type tokenBucket struct {
+	name   string  // For errors, e.g. "tool search"
+	rate   float64 // Tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(name string, limit RateLimit) (*tokenBucket, error) {
+	count, window, err := parseRate(limit.Rate)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit for %s: %w", name, err)
+	}
+	return bucketFor(name, count, window, limit.Burst), nil
+}
+
+// bucketFor returns a full bucket refilling count tokens per window. A burst
+// of 0 holds count tokens.
+func bucketFor(name string, count int, window time.Duration, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = count
+	}
+	return &tokenBucket{
+		name:   name,
+		rate:   float64(count) / window.Seconds(),
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// wait refills the bucket and returns how long until it has a token,
+// rounded to the millisecond so float error does not add a second to
+// retry-after hints
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+b.rate*now.Sub(b.last).Seconds())
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second)).Round(time.Millisecond)
+}
+
+// rateLimiter is a Handler that applies RateLimits
+type rateLimiter struct {
+	next    Handler
+	maxWait time.Duration
+	logf    func(format string, v ...interface{})
+	now     func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	mu      sync.Mutex
+	global  *tokenBucket
+	methods map[string]*tokenBucket
+	tools   map[string]*tokenBucket
+}
+
+func newRateLimiter(next Handler, limits RateLimits, logf func(string, ...interface{})) (Handler, error) {
+	if limits.IsZero() {
+		return next, nil
+	}
+	r := &rateLimiter{
+		next:    next,
+		maxWait: limits.MaxWait,
+		logf:    logf,
+		now:     time.Now,
+		sleep:   sleepContext,
+		methods: map[string]*tokenBucket{},
+		tools:   map[string]*tokenBucket{},
+	}
+	var err error
+	if limits.Global.Rate != "" {
+		if r.global, err = newTokenBucket("all requests", limits.Global); err != nil {
+			return nil, err
+		}
+	}
+	for method, limit := range limits.Methods {
+		if r.methods[method], err = newTokenBucket("method "+method, limit); err != nil {
+			return nil, err
+		}
+	}
+	for tool, limit := range limits.Tools {
+		if r.tools[tool], err = newTokenBucket("tool "+tool, limit); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Handle implements Handler
+func (r *rateLimiter) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	// Notifications and the handshake are never throttled
+	if req.ID == nil || req.Method == "initialize" {
+		return r.next.Handle(ctx, req)
+	}
+
+	buckets := []*tokenBucket{r.global, r.methods[req.Method]}
+	if req.Method == "tools/call" {
+		params, _ := decodeParams(req)
+		name, _ := params["name"].(string)
+		buckets = append(buckets, r.tools[name])
+	}
+
+	r.mu.Lock()
+	now := r.now()
+	var wait time.Duration
+	var limiting *tokenBucket
+	for _, b := range buckets {
+		if b == nil {
+			continue
+		}
+		if w := b.wait(now); w > wait {
+			wait, limiting = w, b
+		}
+	}
+	if wait > r.maxWait {
+		r.mu.Unlock()
+		seconds := math.Ceil(wait.Seconds())
+		r.logf("Rate limit for %s exceeded, rejecting %s", limiting.name, req.Method)
+		return jsonrpc.NewError(req.ID, jsonrpc.InvalidRequest,
+			fmt.Sprintf("Rate limit for %s exceeded; retry in %.0fs", limiting.name, seconds),
+			map[string]any{"limit": limiting.name, "retryAfter": seconds}), nil
+	}
+	// Take the tokens now so requests queue in order
+	for _, b := range buckets {
+		if b != nil {
+			b.tokens--
+		}
+	}
+	r.mu.Unlock()
+
+	if wait > 0 {
+		r.logf("Rate limit for %s reached, delaying %s by %v", limiting.name, req.Method, wait.Round(time.Millisecond))
+		if err := r.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+	return r.next.Handle(ctx, req)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
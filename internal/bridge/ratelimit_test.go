@@ -0,0 +1,80 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var slept []time.Duration
+	remote := &fakeMCPServer{name: "search"}
+	handler, err := newRateLimiter(remote, RateLimits{
+		Global:  RateLimit{Rate: "100/s"},
+		Methods: map[string]RateLimit{"resources/read": {Rate: "1/min"}},
+		Tools:   map[string]RateLimit{"search": {Rate: "2/min", Burst: 1}},
+		MaxWait: 10 * time.Second,
+	}, func(string, ...interface{}) {})
+	if err != nil {
+		t.Fatalf("newRateLimiter failed: %v", err)
+	}
+	r := handler.(*rateLimiter)
+	r.now = func() time.Time { return now }
+	r.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		now = now.Add(d)
+		return nil
+	}
+
+	call := func(method, params string) *jsonrpc.Response {
+		t.Helper()
+		resp, err := r.Handle(context.Background(), &jsonrpc.Request{JSONRPC: jsonrpc.Version, ID: 1, Method: method, Params: []byte(params)})
+		if err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+		return resp
+	}
+
+	if resp := call("tools/call", `{"name":"search"}`); resp.Error != nil {
+		t.Fatalf("Expected the first search through, got %v", resp.Error)
+	}
+	// The bucket refills one token every 30s, more than MaxWait
+	resp := call("tools/call", `{"name":"search"}`)
+	if resp.Error == nil || resp.Error.Code != jsonrpc.InvalidRequest ||
+		!strings.Contains(string(resp.Error.Data), `"retryAfter":30`) || !strings.Contains(resp.Error.Message, "tool search") {
+		t.Fatalf("Expected search to be rejected with retry-after data, got %+v", resp.Error)
+	}
+	if resp := call("tools/call", `{"name":"other"}`); resp.Error != nil {
+		t.Errorf("Expected other tools to be unaffected, got %v", resp.Error)
+	}
+
+	// Within MaxWait the request is delayed instead of rejected
+	now = now.Add(25 * time.Second)
+	if resp := call("tools/call", `{"name":"search"}`); resp.Error != nil {
+		t.Fatalf("Expected search to be queued, got %v", resp.Error)
+	}
+	if len(slept) != 1 || slept[0].Round(time.Millisecond) != 5*time.Second {
+		t.Errorf("Expected a 5s wait, got %v", slept)
+	}
+
+	if resp := call("resources/read", `{"uri":"file:///a"}`); resp.Error != nil {
+		t.Errorf("Expected the first read through, got %v", resp.Error)
+	}
+	if resp := call("resources/read", `{"uri":"file:///a"}`); resp.Error == nil {
+		t.Error("Expected the second read to exceed the method limit")
+	}
+	if len(remote.calls) != 4 {
+		t.Errorf("Expected rejected requests to stay local, got %v", remote.calls)
+	}
+}
+
+func TestRateLimiterErrors(t *testing.T) {
+	_, err := newRateLimiter(&fakeMCPServer{}, RateLimits{Tools: map[string]RateLimit{"search": {Rate: "fast"}}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "tool search") {
+		t.Errorf("Expected an invalid rate to be reported, got %v", err)
+	}
+}
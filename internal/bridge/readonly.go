@@ -0,0 +1,113 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// readOnlyHandler restricts the client to tools that declare themselves
+// read-only. Tools annotated destructiveHint are always blocked, tools with
+// readOnlyHint decide by its value, and tools that state neither are only
+// allowed when they match an allow pattern. Blocked tools are left out of
+// tools/list and calls to them fail with MethodNotFound.
+type readOnlyHandler struct {
+	next  Handler
+	allow *matcher // Trusted tools without a readOnlyHint; nil trusts none
+
+	mu          sync.Mutex
+	annotations map[string]map[string]any // From tools/list, by tool name
+}
+
+func newReadOnlyHandler(next Handler, enabled bool, allow []string) (Handler, error) {
+	if !enabled {
+		return next, nil
+	}
+	m, err := Filter{Allow: allow}.compile()
+	if err != nil {
+		return nil, fmt.Errorf("read-only allow list: %w", err)
+	}
+	return &readOnlyHandler{next: next, allow: m}, nil
+}
+
+// Handle implements Handler
+func (h *readOnlyHandler) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	switch req.Method {
+	case "tools/list":
+		resp, err := h.next.Handle(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		params, _ := decodeParams(req)
+		_, paged := params["cursor"]
+		editResult(resp, func(result map[string]any) {
+			tools, _ := result["tools"].([]any)
+			result["tools"] = h.learn(tools, !paged)
+		})
+		return resp, nil
+	case "tools/call":
+	default:
+		return h.next.Handle(ctx, req)
+	}
+
+	params, err := decodeParams(req)
+	if err != nil {
+		return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, err.Error(), nil), nil
+	}
+	name, _ := params["name"].(string)
+	h.mu.Lock()
+	annotations, known := h.annotations[name]
+	h.mu.Unlock()
+	if !known {
+		// The client has not listed tools yet, so look the tool up
+		tools, err := listAll(ctx, h.next, &jsonrpc.Request{JSONRPC: jsonrpc.Version, ID: "mcp-bridge-read-only", Method: "tools/list"}, "tools")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tools for read-only mode: %w", err)
+		}
+		h.learn(tools, true)
+		h.mu.Lock()
+		annotations, known = h.annotations[name]
+		h.mu.Unlock()
+	}
+	if !known || !h.safe(name, annotations) {
+		return jsonrpc.NewError(req.ID, jsonrpc.MethodNotFound, fmt.Sprintf("Tool %s is not available in read-only mode", name), nil), nil
+	}
+	return h.next.Handle(ctx, req)
+}
+
+// learn records the annotations of a page of tools, starting over on the
+// first page, and returns the tools that are safe to show
+func (h *readOnlyHandler) learn(tools []any, first bool) []any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if first || h.annotations == nil {
+		h.annotations = map[string]map[string]any{}
+	}
+	safe := []any{}
+	for _, item := range tools {
+		tool, _ := item.(map[string]any)
+		name, _ := tool["name"].(string)
+		annotations, _ := tool["annotations"].(map[string]any)
+		if annotations == nil {
+			annotations = map[string]any{}
+		}
+		h.annotations[name] = annotations
+		if h.safe(name, annotations) {
+			safe = append(safe, item)
+		}
+	}
+	return safe
+}
+
+// safe applies the read-only rules to one tool
+func (h *readOnlyHandler) safe(name string, annotations map[string]any) bool {
+	if destructive, _ := annotations["destructiveHint"].(bool); destructive {
+		return false
+	}
+	if readOnly, stated := annotations["readOnlyHint"].(bool); stated {
+		return readOnly
+	}
+	return h.allow != nil && h.allow.visible(name)
+}
@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestReadOnlyHandler(t *testing.T) {
+	remote := &fakeMCPServer{
+		name:     "prod",
+		pageSize: 2,
+		tools: []map[string]any{
+			{"name": "get_issue", "annotations": map[string]any{"readOnlyHint": true}},
+			{"name": "update_issue", "annotations": map[string]any{"readOnlyHint": false}},
+			{"name": "drop_db", "annotations": map[string]any{"readOnlyHint": true, "destructiveHint": true}},
+			{"name": "search"},
+			{"name": "run_query"},
+		},
+	}
+	h, err := newReadOnlyHandler(remote, true, []string{"search", "drop_*"})
+	if err != nil {
+		t.Fatalf("newReadOnlyHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"update_issue"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/list","params":{"cursor":"2"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/list","params":{"cursor":"4"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"get_issue"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"search"}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"drop_db"}}`,
+		`{"jsonrpc":"2.0","id":8,"method":"tools/call","params":{"name":"run_query"}}`,
+	)
+
+	var names []string
+	for _, resp := range responses[1:4] {
+		for _, tool := range resp["result"].(map[string]any)["tools"].([]any) {
+			names = append(names, tool.(map[string]any)["name"].(string))
+		}
+	}
+	if got := strings.Join(names, ","); got != "get_issue,search" {
+		t.Errorf("Expected only read-only and allowlisted tools, got %s", got)
+	}
+
+	for _, i := range []int{0, 6, 7} {
+		errObj, _ := responses[i]["error"].(map[string]any)
+		if errObj == nil || errObj["code"] != float64(jsonrpc.MethodNotFound) {
+			t.Errorf("Expected request %d to be blocked, got %v", i+1, responses[i])
+		}
+	}
+	for _, i := range []int{4, 5} {
+		if responses[i]["result"] == nil {
+			t.Errorf("Expected request %d to be forwarded, got %v", i+1, responses[i])
+		}
+	}
+	for _, call := range remote.calls {
+		if call == "tools/call update_issue" || call == "tools/call drop_db" || call == "tools/call run_query" {
+			t.Errorf("Expected blocked tools to stay local, got %v", remote.calls)
+		}
+	}
+}
+
+func TestReadOnlyRejectsStreaming(t *testing.T) {
+	var contacted atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contacted.Store(true)
+	}))
+	defer server.Close()
+
+	b := New(server.URL, "", false)
+	b.Transport = TransportStreaming
+	b.ReadOnly = true
+	err := b.Run()
+	if err == nil || !strings.Contains(err.Error(), "read-only mode") {
+		t.Fatalf("Expected read-only mode to be rejected with the streaming transport, got %v", err)
+	}
+	if contacted.Load() {
+		t.Error("Expected the bridge to fail before contacting the server")
+	}
+}
@@ -0,0 +1,207 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactedValue replaces secrets in logs
+const redactedValue = "[REDACTED]"
+
+// Redaction masks secrets in debug logs on top of the credentials the bridge
+// knows about (API keys, signing secrets, proxy passwords, sensitive header
+// values and URL query parameters) and message fields with well-known
+// secret names such as password or client_secret.
+type Redaction struct {
+	// Paths into JSON-RPC messages whose values are masked, such as
+	// params.arguments.password; "*" matches any key or array index
+	Paths []string
+	// Patterns are regular expressions whose matches are masked
+	Patterns []string
+}
+
+// sensitiveKeys are message field names masked by default, compared after
+// lowercasing and dropping "_" and "-"
+var sensitiveKeys = map[string]bool{
+	"password": true, "passwd": true, "secret": true, "clientsecret": true,
+	"apikey": true, "token": true, "accesstoken": true, "refreshtoken": true,
+	"idtoken": true, "authorization": true,
+}
+
+// sensitiveName reports whether a header or query parameter name suggests a
+// credential
+func sensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"auth", "token", "key", "secret", "password", "signature", "cookie", "credential"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactor masks secrets in log output. A nil redactor leaves text alone.
+type redactor struct {
+	secrets  []string // Literal values, longest first
+	paths    [][]string
+	patterns []*regexp.Regexp
+}
+
+func newRedactor(config Redaction, secrets []string) (*redactor, error) {
+	r := &redactor{}
+	for _, path := range config.Paths {
+		r.paths = append(r.paths, strings.Split(path, "."))
+	}
+	for _, pattern := range config.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	seen := map[string]bool{}
+	for _, secret := range secrets {
+		if secret != "" && secret != redactedValue && !seen[secret] {
+			seen[secret] = true
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+	// Longest first so a secret containing another is masked whole
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+	return r, nil
+}
+
+// urlSecrets returns the password and sensitive query parameter values of a
+// URL
+func urlSecrets(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	var secrets []string
+	if password, ok := u.User.Password(); ok {
+		secrets = append(secrets, password, url.PathEscape(password))
+	}
+	for name, values := range u.Query() {
+		if sensitiveName(name) {
+			for _, value := range values {
+				secrets = append(secrets, value, url.QueryEscape(value))
+			}
+		}
+	}
+	return secrets
+}
+
+// String masks known secrets and pattern matches in s
+func (r *redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redactedValue)
+	}
+	return s
+}
+
+// JSON masks a JSON-RPC message given as text. Text that is not JSON is
+// treated as a plain string.
+func (r *redactor) JSON(data []byte) string {
+	if r == nil {
+		return string(data)
+	}
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err == nil && r.mask(value, nil) {
+		if masked, err := json.Marshal(value); err == nil {
+			data = masked
+		}
+	}
+	return r.String(string(data))
+}
+
+// Value masks a message that has been decoded or is about to be encoded and
+// returns it as indented JSON
+func (r *redactor) Value(msg any) (string, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+	if r != nil {
+		r.mask(value, nil)
+	}
+	data, err = json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return r.String(string(data)), nil
+}
+
+// mask replaces sensitive fields of value in place and reports whether it
+// changed anything. path is the location of value within the message.
+func (r *redactor) mask(value any, path []string) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			childPath := append(path[:len(path):len(path)], key)
+			if r.sensitive(key, childPath) {
+				if child != redactedValue {
+					v[key] = redactedValue
+					changed = true
+				}
+				continue
+			}
+			changed = r.mask(child, childPath) || changed
+		}
+	case []any:
+		for i, child := range v {
+			childPath := append(path[:len(path):len(path)], fmt.Sprint(i))
+			if r.matchesPath(childPath) {
+				v[i] = redactedValue
+				changed = true
+				continue
+			}
+			changed = r.mask(child, childPath) || changed
+		}
+	}
+	return changed
+}
+
+func (r *redactor) sensitive(key string, path []string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	return sensitiveKeys[normalized] || r.matchesPath(path)
+}
+
+func (r *redactor) matchesPath(path []string) bool {
+	for _, pattern := range r.paths {
+		if len(pattern) != len(path) {
+			continue
+		}
+		match := true
+		for i, segment := range pattern {
+			if segment != "*" && segment != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestRedactor(t *testing.T) {
+	r, err := newRedactor(Redaction{
+		Paths:    []string{"params.arguments.pin", "result.content.*.text"},
+		Patterns: []string{`ghp_[A-Za-z0-9]+`},
+	}, []string{"sk-live-123", ""})
+	if err != nil {
+		t.Fatalf("newRedactor failed: %v", err)
+	}
+
+	got := r.JSON([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"login","progressToken":7,` +
+		`"arguments":{"user":"ann","password":"hunter2","pin":1234,"note":"key sk-live-123, token ghp_abc123"}}}`))
+	for _, secret := range []string{"hunter2", "1234", "sk-live-123", "ghp_abc123"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("Expected %s to be masked in %s", secret, got)
+		}
+	}
+	for _, kept := range []string{`"user":"ann"`, `"progressToken":7`, `"name":"login"`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("Expected %s to survive in %s", kept, got)
+		}
+	}
+
+	got, err = r.Value(map[string]any{"result": map[string]any{"content": []any{
+		map[string]any{"type": "text", "text": "private"},
+	}}})
+	if err != nil || strings.Contains(got, "private") || !strings.Contains(got, `"type": "text"`) {
+		t.Errorf("Expected wildcard path to mask the text only, got %s (%v)", got, err)
+	}
+
+	if got := r.JSON([]byte("not json sk-live-123")); got != "not json [REDACTED]" {
+		t.Errorf("Expected plain text to be masked, got %s", got)
+	}
+	if _, err := newRedactor(Redaction{Patterns: []string{"("}}, nil); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
+
+func TestBridgeRedactsDebugLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"token":"session-secret"}}`))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logs)
+
+	b := New(server.URL+"/mcp?access_token=url-secret", "key-one,key-two", true)
+	b.Headers = map[string]string{"X-Api-Key": "header-secret", "X-Tenant": "acme"}
+	b.Redact = Redaction{Paths: []string{"params.arguments.query"}}
+	h, err := b.postHandler()
+	if err != nil {
+		t.Fatalf("postHandler failed: %v", err)
+	}
+	b.LogMCPClient("Request", map[string]any{"apiKey": "key-two", "tenant": "acme"})
+	_, err = h.Handle(context.Background(), &jsonrpc.Request{
+		JSONRPC: jsonrpc.Version, ID: 1, Method: "tools/call",
+		Params: []byte(`{"name":"search","arguments":{"query":"customer 42"}}`),
+	})
+	if err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+
+	out := logs.String()
+	for _, secret := range []string{"url-secret", "key-one", "key-two", "session-secret", "customer 42"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Expected %s to be masked in logs:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "acme") || !strings.Contains(out, "[REDACTED]") {
+		t.Errorf("Expected masked but readable logs:\n%s", out)
+	}
+}
@@ -0,0 +1,197 @@
+package bridge
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// schemaError is one validation failure, located by a JSON pointer into the
+// validated value
+type schemaError struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+func (e schemaError) String() string {
+	pointer := e.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return pointer + ": " + e.Message
+}
+
+// joinSchemaErrors formats errs for an error message
+func joinSchemaErrors(errs []schemaError) string {
+	parts := make([]string, len(errs))
+	for i, e := range errs {
+		parts[i] = e.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// toolSchema is a tool's input or output schema prepared for validation
+type toolSchema struct {
+	root     *jsonschema.Schema
+	resolved *jsonschema.Resolved
+}
+
+// compileSchema prepares a schema taken from a tool definition. Schemas are
+// validated with draft 2020-12 rules whatever $schema they declare, which
+// covers the keywords tool schemas use in practice.
+func compileSchema(raw any) (*toolSchema, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	root := &jsonschema.Schema{}
+	if err := json.Unmarshal(data, root); err != nil {
+		return nil, err
+	}
+	root.Schema = ""
+	resolved, err := root.Resolve(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &toolSchema{root: root, resolved: resolved}, nil
+}
+
+// validate checks value against the schema and returns every failure it can
+// attribute to a location in value
+func (t *toolSchema) validate(value any) []schemaError {
+	err := t.resolved.Validate(value)
+	if err == nil {
+		return nil
+	}
+	var errs []schemaError
+	t.walk(t.root, value, "", &errs)
+	if len(errs) == 0 {
+		errs = append(errs, schemaError{Message: cleanSchemaMessage(err)})
+	}
+	return errs
+}
+
+// walk descends through properties and items so that each failure is
+// reported at its own pointer, validating the remaining keywords of every
+// node with the library
+func (t *toolSchema) walk(s *jsonschema.Schema, value any, pointer string, errs *[]schemaError) {
+	s = t.deref(s)
+	if s == nil {
+		return
+	}
+	node := s
+
+	switch v := value.(type) {
+	case map[string]any:
+		if len(s.Properties) > 0 || len(s.Required) > 0 || s.AdditionalProperties != nil {
+			for _, name := range s.Required {
+				if _, ok := v[name]; !ok {
+					*errs = append(*errs, schemaError{pointer + "/" + escapePointer(name), "required property is missing"})
+				}
+			}
+			for _, name := range sortedKeys(v) {
+				child := pointer + "/" + escapePointer(name)
+				if prop, ok := s.Properties[name]; ok {
+					t.walk(prop, v[name], child, errs)
+				} else if isFalseSchema(s.AdditionalProperties) {
+					*errs = append(*errs, schemaError{child, "unexpected property"})
+				} else if s.AdditionalProperties != nil {
+					t.walk(s.AdditionalProperties, v[name], child, errs)
+				}
+			}
+			clone := *s
+			clone.Properties, clone.Required, clone.AdditionalProperties = nil, nil, nil
+			node = &clone
+		}
+	case []any:
+		if s.Items != nil || len(s.PrefixItems) > 0 {
+			for i, item := range v {
+				sub := s.Items
+				if i < len(s.PrefixItems) {
+					sub = s.PrefixItems[i]
+				}
+				t.walk(sub, item, pointer+"/"+strconv.Itoa(i), errs)
+			}
+			clone := *s
+			clone.Items, clone.PrefixItems = nil, nil
+			node = &clone
+		}
+	}
+
+	if err := t.validateNode(node, value); err != nil {
+		*errs = append(*errs, schemaError{pointer, cleanSchemaMessage(err)})
+	}
+}
+
+// validateNode validates value against a single subschema, carrying over the
+// root's definitions so local references still resolve
+func (t *toolSchema) validateNode(s *jsonschema.Schema, value any) error {
+	node := s.CloneSchemas()
+	node.Schema, node.ID = "", ""
+	if node.Defs == nil {
+		node.Defs = t.root.Defs
+	}
+	if node.Definitions == nil {
+		node.Definitions = t.root.Definitions
+	}
+	resolved, err := node.Resolve(nil)
+	if err != nil {
+		return nil
+	}
+	return resolved.Validate(value)
+}
+
+// deref follows local references to definitions
+func (t *toolSchema) deref(s *jsonschema.Schema) *jsonschema.Schema {
+	for i := 0; s != nil && s.Ref != "" && i < 8; i++ {
+		var defs map[string]*jsonschema.Schema
+		name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+		if ok {
+			defs = t.root.Defs
+		} else if name, ok = strings.CutPrefix(s.Ref, "#/definitions/"); ok {
+			defs = t.root.Definitions
+		}
+		target, found := defs[unescapePointer(name)]
+		if !found {
+			return s
+		}
+		s = target
+	}
+	return s
+}
+
+func isFalseSchema(s *jsonschema.Schema) bool {
+	return s != nil && s.Not != nil && reflect.DeepEqual(*s.Not, jsonschema.Schema{})
+}
+
+var schemaMessagePrefix = regexp.MustCompile(`^(validating [^:]+: )+`)
+
+// cleanSchemaMessage drops the library's "validating <schema>:" context,
+// which describes schema locations rather than the value
+func cleanSchemaMessage(err error) string {
+	return schemaMessagePrefix.ReplaceAllString(err.Error(), "")
+}
+
+// escapePointer escapes a JSON pointer reference token (RFC 6901)
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func unescapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
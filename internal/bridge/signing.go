@@ -0,0 +1,347 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer adds a signature to an outgoing request. body holds the complete
+// request body, which remains readable from req.
+type Signer interface {
+	Sign(req *http.Request, body []byte) error
+}
+
+// signTransport signs every request with a Signer before sending it
+type signTransport struct {
+	base   http.RoundTripper
+	signer Signer
+}
+
+func (t *signTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body for signing: %w", err)
+		}
+	}
+
+	signed := req.Clone(req.Context())
+	signed.Body = io.NopCloser(bytes.NewReader(body))
+	signed.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	signed.ContentLength = int64(len(body))
+
+	if err := t.signer.Sign(signed, body); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+	return t.base.RoundTrip(signed)
+}
+
+// HMAC canonicalization modes, selecting what the signature covers
+const (
+	HMACCanonicalBody          = "body"           // the raw body
+	HMACCanonicalTimestampBody = "timestamp-body" // "<timestamp>.<body>"
+	HMACCanonicalRequest       = "request"        // "<METHOD>\n<path?query>\n<timestamp>\n<body>"
+)
+
+// HMACSigner signs requests with a shared secret
+type HMACSigner struct {
+	Secret          []byte
+	Algorithm       string // "sha256" (default) or "sha512"
+	Header          string // Signature header, default "X-Signature"
+	TimestampHeader string // Unix timestamp header, default "X-Timestamp"
+	Canonical       string // One of the HMACCanonical* modes, default body
+	Encoding        string // "hex" (default) or "base64"
+	Prefix          string // Prepended to the encoded signature, e.g. "sha256="
+
+	now func() time.Time
+}
+
+// Sign implements Signer
+func (s *HMACSigner) Sign(req *http.Request, body []byte) error {
+	if len(s.Secret) == 0 {
+		return errors.New("HMAC secret is empty")
+	}
+
+	var newHash func() hash.Hash
+	switch s.Algorithm {
+	case "", "sha256":
+		newHash = sha256.New
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return fmt.Errorf("unsupported HMAC algorithm: %s", s.Algorithm)
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+
+	mac := hmac.New(newHash, s.Secret)
+	switch s.Canonical {
+	case "", HMACCanonicalBody:
+	case HMACCanonicalTimestampBody:
+		fmt.Fprintf(mac, "%s.", timestamp)
+	case HMACCanonicalRequest:
+		fmt.Fprintf(mac, "%s\n%s\n%s\n", req.Method, req.URL.RequestURI(), timestamp)
+	default:
+		return fmt.Errorf("unsupported HMAC canonicalization: %s", s.Canonical)
+	}
+	mac.Write(body)
+
+	var signature string
+	switch s.Encoding {
+	case "", "hex":
+		signature = hex.EncodeToString(mac.Sum(nil))
+	case "base64":
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	default:
+		return fmt.Errorf("unsupported signature encoding: %s", s.Encoding)
+	}
+
+	req.Header.Set(orDefault(s.TimestampHeader, "X-Timestamp"), timestamp)
+	req.Header.Set(orDefault(s.Header, "X-Signature"), s.Prefix+signature)
+	return nil
+}
+
+// AWSCredentials holds the keys used for Signature Version 4
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// LoadAWSCredentials reads credentials from the AWS_* environment variables,
+// falling back to the shared credentials file for the given profile
+func LoadAWSCredentials(profile string) (AWSCredentials, error) {
+	creds := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if profile == "" && creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
+		return creds, nil
+	}
+
+	if profile == "" {
+		profile = orDefault(os.Getenv("AWS_PROFILE"), "default")
+	}
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("failed to locate AWS credentials file: %w", err)
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	return loadAWSCredentialsFile(path, profile)
+}
+
+// loadAWSCredentialsFile parses one profile from an INI-style credentials file
+func loadAWSCredentialsFile(path, profile string) (AWSCredentials, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to read AWS credentials: %w", err)
+	}
+	defer f.Close()
+
+	var creds AWSCredentials
+	var section string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to read AWS credentials: %w", err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("no AWS credentials for profile %q in %s", profile, path)
+	}
+	return creds, nil
+}
+
+// SigV4Signer signs requests with AWS Signature Version 4
+type SigV4Signer struct {
+	Credentials AWSCredentials
+	Region      string
+	Service     string // e.g. "execute-api" for API Gateway
+
+	now func() time.Time
+}
+
+// Sign implements Signer
+func (s *SigV4Signer) Sign(req *http.Request, body []byte) error {
+	if s.Credentials.AccessKeyID == "" || s.Credentials.SecretAccessKey == "" {
+		return errors.New("AWS credentials are missing")
+	}
+	if s.Region == "" || s.Service == "" {
+		return errors.New("SigV4 region and service are required")
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.Credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.Credentials.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigV4CanonicalPath(req.URL.EscapedPath()),
+		sigV4CanonicalQuery(req),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.Region, s.Service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.Credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.Credentials.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sigV4CanonicalPath URI-encodes each segment of an already escaped path, as
+// required for every service other than S3
+func sigV4CanonicalPath(escaped string) string {
+	if escaped == "" {
+		return "/"
+	}
+	segments := strings.Split(escaped, "/")
+	for i, segment := range segments {
+		segments[i] = sigV4Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func sigV4CanonicalQuery(req *http.Request) string {
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, sigV4Escape(key)+"="+sigV4Escape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape percent-encodes everything except RFC 3986 unreserved characters
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,179 @@
+package bridge
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hmacVerifier stands in for an API gateway that checks request signatures
+func hmacVerifier(secret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(r.Header.Get("X-Timestamp") + "."))
+		mac.Write(body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(r.Header.Get("X-Hub-Signature")), []byte(want)) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestHMACSigning(t *testing.T) {
+	srv := httptest.NewServer(hmacVerifier("shared-secret"))
+	defer srv.Close()
+
+	post := func(secret string) int {
+		b := New(srv.URL, "test-key", false)
+		b.Signer = &HMACSigner{
+			Secret:    []byte(secret),
+			Header:    "X-Hub-Signature",
+			Canonical: HMACCanonicalTimestampBody,
+			Prefix:    "sha256=",
+		}
+		client, err := b.httpClient("")
+		if err != nil {
+			t.Fatalf("Failed to build client: %v", err)
+		}
+		resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"ping","id":1}`))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("shared-secret"); code != http.StatusOK {
+		t.Errorf("Expected signed request to be accepted, got %d", code)
+	}
+	if code := post("wrong-secret"); code != http.StatusUnauthorized {
+		t.Errorf("Expected request signed with wrong secret to be rejected, got %d", code)
+	}
+}
+
+// TestSigV4Signing checks the signer against the get-vanilla case from the
+// AWS Signature Version 4 test suite
+func TestSigV4Signing(t *testing.T) {
+	signer := &SigV4Signer{
+		Credentials: AWSCredentials{
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		},
+		Region:  "us-east-1",
+		Service: "service",
+		now: func() time.Time {
+			return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+		},
+	}
+
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	if err := signer.Sign(req, nil); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Unexpected Authorization header:\n got: %s\nwant: %s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("Unexpected X-Amz-Date %q", got)
+	}
+}
+
+func TestLoadAWSCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	os.WriteFile(path, []byte(`[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = default-secret
+
+[gateway]
+aws_access_key_id = AKIDGATEWAY
+aws_secret_access_key = gateway-secret
+aws_session_token = token
+`), 0o600)
+
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "")
+
+	creds, err := LoadAWSCredentials("gateway")
+	if err != nil {
+		t.Fatalf("LoadAWSCredentials failed: %v", err)
+	}
+	if creds.AccessKeyID != "AKIDGATEWAY" || creds.SessionToken != "token" {
+		t.Errorf("Unexpected credentials for profile: %+v", creds)
+	}
+
+	creds, err = LoadAWSCredentials("")
+	if err != nil || creds.AccessKeyID != "AKIDDEFAULT" {
+		t.Errorf("Expected default profile credentials, got %+v (%v)", creds, err)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	creds, err = LoadAWSCredentials("")
+	if err != nil || creds.AccessKeyID != "AKIDENV" {
+		t.Errorf("Expected environment credentials, got %+v (%v)", creds, err)
+	}
+}
+
+func TestSigV4SignsCustomHeaders(t *testing.T) {
+	signer := &SigV4Signer{
+		Credentials: AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"},
+		Region:      "eu-west-1",
+		Service:     "execute-api",
+		now:         func() time.Time { return time.Date(2025, 10, 3, 17, 40, 43, 0, time.UTC) },
+	}
+	// The gateway signs what it received and compares
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		check, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), bytes.NewReader(body))
+		for name, values := range r.Header {
+			if name != "Authorization" {
+				check.Header[name] = values
+			}
+		}
+		if err := signer.Sign(check, body); err != nil || check.Header.Get("Authorization") != r.Header.Get("Authorization") {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	b := New(srv.URL, "", false)
+	b.Signer = signer
+	b.Headers = map[string]string{"X-Amz-Target": "mcp.Invoke"}
+	client, err := b.httpClient("")
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	resp, err := client.Post(srv.URL+"/mcp", "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"ping","id":1}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the custom header to be covered by the signature, got %d", resp.StatusCode)
+	}
+
+	b.APIKey = "test-key"
+	if _, err := b.httpClient(""); err == nil {
+		t.Error("Expected an API key to be rejected with SigV4 signing")
+	}
+}
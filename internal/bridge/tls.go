@@ -0,0 +1,125 @@
+package bridge
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSOptions configures how the bridge authenticates itself to the remote
+// MCP server and how it verifies the server's certificate.
+type TLSOptions struct {
+	CertFile   string   // Client certificate (PEM) presented for mutual TLS
+	KeyFile    string   // Private key (PEM) matching CertFile
+	CAFiles    []string // Extra CA bundles trusted in addition to the system roots
+	MinVersion string   // Minimum TLS version: "1.0", "1.1", "1.2" or "1.3"
+	ServerName string   // Overrides the SNI and verification host name
+	Pins       []string // SPKI pins as "sha256/<base64>"; any one must match the chain
+}
+
+// IsZero reports whether no TLS options have been set
+func (o TLSOptions) IsZero() bool {
+	return o.CertFile == "" && o.KeyFile == "" && len(o.CAFiles) == 0 &&
+		o.MinVersion == "" && o.ServerName == "" && len(o.Pins) == 0
+}
+
+// Config builds a tls.Config from the options
+func (o TLSOptions) Config() (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: o.ServerName}
+
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(o.CAFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, file := range o.CAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", file)
+			}
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.MinVersion != "" {
+		version, err := parseTLSVersion(o.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		cfg.MinVersion = version
+	}
+
+	if len(o.Pins) > 0 {
+		pins := make(map[string]bool, len(o.Pins))
+		for _, pin := range o.Pins {
+			hash, ok := strings.CutPrefix(pin, "sha256/")
+			if !ok {
+				return nil, fmt.Errorf("unsupported pin %q: expected sha256/<base64>", pin)
+			}
+			if raw, err := base64.StdEncoding.DecodeString(hash); err != nil || len(raw) != sha256.Size {
+				return nil, fmt.Errorf("invalid pin %q: not a base64 SHA-256 digest", pin)
+			}
+			pins[hash] = true
+		}
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPins(cs, pins)
+		}
+	}
+
+	return cfg, nil
+}
+
+// SPKIPin returns the "sha256/<base64>" pin for a certificate's public key
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// verifyPins checks that at least one certificate in the verified chain
+// carries one of the pinned public keys
+func verifyPins(cs tls.ConnectionState, pins map[string]bool) error {
+	certs := cs.PeerCertificates
+	if len(cs.VerifiedChains) > 0 {
+		certs = cs.VerifiedChains[0]
+	}
+	for _, cert := range certs {
+		if pins[strings.TrimPrefix(SPKIPin(cert), "sha256/")] {
+			return nil
+		}
+	}
+	return errors.New("server certificate does not match any pinned public key")
+}
+
+func parseTLSVersion(v string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(v), "tls") {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version: %s", v)
+	}
+}
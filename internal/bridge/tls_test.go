@@ -0,0 +1,188 @@
+package bridge
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a generated certificate together with its PEM files on disk
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func newTestCert(t *testing.T, dir, name string, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"mcp.internal"},
+	}
+	if isCA {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	os.WriteFile(c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return c
+}
+
+// newMTLSServer starts a TLS server signed by a private CA that requires
+// client certificates from the same CA
+func newMTLSServer(t *testing.T) (*httptest.Server, *testCert, *testCert, *testCert) {
+	t.Helper()
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil, true)
+	serverCert := newTestCert(t, dir, "server", ca, false)
+	clientCert := newTestCert(t, dir, "client", ca, false)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert.tlsCertificate()},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv, ca, serverCert, clientCert
+}
+
+func TestTLSOptions(t *testing.T) {
+	srv, ca, serverCert, clientCert := newMTLSServer(t)
+
+	get := func(opts TLSOptions) error {
+		b := New(srv.URL, "", false)
+		b.TLS = opts
+		client, err := b.httpClient("")
+		if err != nil {
+			return err
+		}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	t.Run("mutual TLS with private CA", func(t *testing.T) {
+		err := get(TLSOptions{
+			CertFile: clientCert.certFile,
+			KeyFile:  clientCert.keyFile,
+			CAFiles:  []string{ca.certFile},
+		})
+		if err != nil {
+			t.Fatalf("Expected mTLS request to succeed: %v", err)
+		}
+	})
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		if err := get(TLSOptions{CAFiles: []string{ca.certFile}}); err == nil {
+			t.Error("Expected request without client certificate to fail")
+		}
+	})
+
+	t.Run("server name override", func(t *testing.T) {
+		err := get(TLSOptions{
+			CertFile:   clientCert.certFile,
+			KeyFile:    clientCert.keyFile,
+			CAFiles:    []string{ca.certFile},
+			ServerName: "mcp.internal",
+		})
+		if err != nil {
+			t.Fatalf("Expected request with SNI override to succeed: %v", err)
+		}
+	})
+
+	t.Run("matching pin", func(t *testing.T) {
+		err := get(TLSOptions{
+			CertFile: clientCert.certFile,
+			KeyFile:  clientCert.keyFile,
+			CAFiles:  []string{ca.certFile},
+			Pins:     []string{SPKIPin(serverCert.cert)},
+		})
+		if err != nil {
+			t.Fatalf("Expected pinned request to succeed: %v", err)
+		}
+	})
+
+	t.Run("mismatched pin", func(t *testing.T) {
+		err := get(TLSOptions{
+			CertFile: clientCert.certFile,
+			KeyFile:  clientCert.keyFile,
+			CAFiles:  []string{ca.certFile},
+			Pins:     []string{SPKIPin(clientCert.cert)},
+		})
+		if err == nil {
+			t.Error("Expected request with mismatched pin to fail")
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		cases := []TLSOptions{
+			{CertFile: clientCert.certFile},
+			{MinVersion: "1.4"},
+			{Pins: []string{"md5/abc"}},
+			{CAFiles: []string{clientCert.keyFile}},
+		}
+		for _, opts := range cases {
+			if _, err := opts.Config(); err == nil {
+				t.Errorf("Expected error for options %+v", opts)
+			}
+		}
+	})
+}
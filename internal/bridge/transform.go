@@ -0,0 +1,210 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// Transform directions
+const (
+	TransformRequests  = "request"
+	TransformResponses = "response"
+	TransformBoth      = "both"
+)
+
+// defaultTransformTimeout bounds one run of a transform command
+const defaultTransformTimeout = 10 * time.Second
+
+// Transform pipes JSON-RPC messages through an external command, much like a
+// git clean/smudge filter. The command receives one message on stdin and
+// prints the message to pass on: nothing leaves it unchanged, a JSON-RPC
+// message replaces it and null drops it. MCP_BRIDGE_DIRECTION and
+// MCP_BRIDGE_METHOD tell the command what it is looking at. The command
+// inherits the bridge's environment without its credentials (see
+// filterEnv). A command that fails or times out fails the request.
+type Transform struct {
+	Command   []string
+	Direction string        // TransformRequests, TransformResponses or TransformBoth (default)
+	Methods   []string      // Glob patterns of methods; default all
+	Timeout   time.Duration // Default 10s
+}
+
+// transformFilter is a compiled Transform
+type transformFilter struct {
+	Transform
+	name      string
+	methods   *matcher
+	requests  bool
+	responses bool
+}
+
+// transformHandler runs client requests through the request filters in
+// order before forwarding them, and their responses through the response
+// filters in order before returning them
+type transformHandler struct {
+	next    Handler
+	filters []*transformFilter
+	logf    func(format string, v ...interface{})
+}
+
+func newTransformHandler(next Handler, transforms []Transform, logf func(string, ...interface{})) (Handler, error) {
+	if len(transforms) == 0 {
+		return next, nil
+	}
+	h := &transformHandler{next: next, logf: logf}
+	for i, t := range transforms {
+		if len(t.Command) == 0 {
+			return nil, fmt.Errorf("transform %d: no command", i+1)
+		}
+		f := &transformFilter{Transform: t, name: t.Command[0]}
+		switch t.Direction {
+		case TransformRequests:
+			f.requests = true
+		case TransformResponses:
+			f.responses = true
+		case "", TransformBoth:
+			f.requests, f.responses = true, true
+		default:
+			return nil, fmt.Errorf("transform %s: unknown direction %q", f.name, t.Direction)
+		}
+		var err error
+		if f.methods, err = (Filter{Allow: t.Methods}).compile(); err != nil {
+			return nil, fmt.Errorf("transform %s: %w", f.name, err)
+		}
+		if f.Timeout <= 0 {
+			f.Timeout = defaultTransformTimeout
+		}
+		h.filters = append(h.filters, f)
+	}
+	return h, nil
+}
+
+// Handle implements Handler
+func (h *transformHandler) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	method := req.Method
+	for _, f := range h.filters {
+		if !f.requests || !f.methods.visible(method) {
+			continue
+		}
+		out, err := f.run(ctx, TransformRequests, method, req)
+		if err != nil {
+			h.logf("Request filter %s failed on %s: %v", f.name, method, err)
+			return h.fail(req, fmt.Sprintf("Request filter %s failed", f.name))
+		}
+		if out == nil {
+			h.logf("Request filter %s dropped %s", f.name, method)
+			if req.ID == nil {
+				return nil, nil
+			}
+			return jsonrpc.NewError(req.ID, jsonrpc.InvalidRequest, fmt.Sprintf("Request dropped by filter %s", f.name), nil), nil
+		}
+		var next jsonrpc.Request
+		if err := json.Unmarshal(out, &next); err != nil || next.Method == "" {
+			h.logf("Request filter %s returned an invalid request for %s", f.name, method)
+			return h.fail(req, fmt.Sprintf("Request filter %s failed", f.name))
+		}
+		// The client matches responses by ID, so filters cannot change it
+		next.JSONRPC, next.ID = jsonrpc.Version, req.ID
+		req = &next
+	}
+
+	resp, err := h.next.Handle(ctx, req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	for _, f := range h.filters {
+		if !f.responses || !f.methods.visible(method) {
+			continue
+		}
+		out, err := f.run(ctx, TransformResponses, method, resp)
+		if err != nil {
+			h.logf("Response filter %s failed on %s: %v", f.name, method, err)
+			return h.fail(req, fmt.Sprintf("Response filter %s failed", f.name))
+		}
+		if out == nil {
+			// The client is waiting for an answer, so a dropped response
+			// still gets one
+			h.logf("Response filter %s dropped the response to %s", f.name, method)
+			return jsonrpc.NewError(req.ID, jsonrpc.InternalError, fmt.Sprintf("Response dropped by filter %s", f.name), nil), nil
+		}
+		var next jsonrpc.Response
+		if err := json.Unmarshal(out, &next); err != nil || (next.Result == nil && next.Error == nil) {
+			h.logf("Response filter %s returned an invalid response for %s", f.name, method)
+			return h.fail(req, fmt.Sprintf("Response filter %s failed", f.name))
+		}
+		next.JSONRPC, next.ID = jsonrpc.Version, resp.ID
+		resp = &next
+	}
+	return resp, nil
+}
+
+// secretEnv are variables the bridge reads credentials from besides its own
+// MCP_BRIDGE_* settings
+var secretEnv = map[string]bool{
+	"AWS_ACCESS_KEY_ID": true, "AWS_SECRET_ACCESS_KEY": true, "AWS_SESSION_TOKEN": true,
+}
+
+// filterEnv drops the bridge's settings, which hold its API keys, signing
+// secrets and proxy credentials, and the AWS credentials it signs with from
+// environ, so filter commands don't see them
+func filterEnv(environ []string) []string {
+	var env []string
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, "MCP_BRIDGE_") && !secretEnv[name] {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// fail answers a request whose filters failed. Notifications get no answer.
+func (h *transformHandler) fail(req *jsonrpc.Request, msg string) (*jsonrpc.Response, error) {
+	if req.ID == nil {
+		return nil, nil
+	}
+	return jsonrpc.NewError(req.ID, jsonrpc.InternalError, msg, nil), nil
+}
+
+// run pipes msg through the command. It returns the message to pass on, or
+// nil when the command drops it.
+func (f *transformFilter) run(ctx context.Context, direction, method string, msg any) ([]byte, error) {
+	input, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, f.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, f.Command[0], f.Command[1:]...)
+	cmd.Env = append(filterEnv(os.Environ()), "MCP_BRIDGE_DIRECTION="+direction, "MCP_BRIDGE_METHOD="+method)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if reason := strings.TrimSpace(stderr.String()); reason != "" {
+			return nil, fmt.Errorf("%w: %s", err, reason)
+		}
+		return nil, err
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	switch {
+	case len(out) == 0:
+		return input, nil
+	case string(out) == "null":
+		return nil, nil
+	}
+	return out, nil
+}
@@ -0,0 +1,104 @@
+package bridge
+
+import (
+	"strings"
+	"testing"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestTransformHandler(t *testing.T) {
+	remote := &fakeMCPServer{name: "remote", tools: []map[string]any{{"name": "search"}}}
+	h, err := newTransformHandler(remote, []Transform{
+		{
+			// Scrub email addresses from tool arguments
+			Command:   []string{"sh", "-c", `sed 's/[a-z]*@example\.com/[email]/g'`},
+			Direction: TransformRequests,
+			Methods:   []string{"tools/*"},
+		},
+		{
+			// Drop everything the filter is told is a ping
+			Command: []string{"sh", "-c", `if [ "$MCP_BRIDGE_METHOD" = ping ]; then echo null; fi`},
+		},
+		{
+			Command:   []string{"sh", "-c", `sed 's/remote ran/remote answered/'`},
+			Direction: TransformResponses,
+		},
+	}, t.Logf)
+	if err != nil {
+		t.Fatalf("newTransformHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search","arguments":{"q":"ann@example.com"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+	)
+
+	text := responses[0]["result"].(map[string]any)["content"].([]any)[0].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "[email]") || strings.Contains(text, "ann@") {
+		t.Errorf("Expected the request filter to scrub the argument, got %q", text)
+	}
+	if !strings.HasPrefix(text, "remote answered") {
+		t.Errorf("Expected the response filter to rewrite the result, got %q", text)
+	}
+	if responses[0]["id"] != float64(1) {
+		t.Errorf("Expected the response to keep the request ID, got %v", responses[0]["id"])
+	}
+	if errObj, _ := responses[1]["error"].(map[string]any); errObj == nil || errObj["code"] != float64(jsonrpc.InvalidRequest) {
+		t.Errorf("Expected the dropped request to be answered with an error, got %v", responses[1])
+	}
+	if len(remote.calls) != 1 {
+		t.Errorf("Expected the dropped request to stay local, got %v", remote.calls)
+	}
+}
+
+func TestTransformHandlerFailsClosed(t *testing.T) {
+	remote := &fakeMCPServer{name: "remote", tools: []map[string]any{{"name": "search"}}}
+	h, err := newTransformHandler(remote, []Transform{
+		{Command: []string{"sh", "-c", "echo broken >&2; exit 3"}, Direction: TransformResponses},
+	}, t.Logf)
+	if err != nil {
+		t.Fatalf("newTransformHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}}`)
+	if errObj, _ := responses[0]["error"].(map[string]any); errObj == nil || errObj["code"] != float64(jsonrpc.InternalError) {
+		t.Errorf("Expected a failed filter to fail the request, got %v", responses[0])
+	}
+}
+
+func TestTransformHandlerErrors(t *testing.T) {
+	for _, transforms := range [][]Transform{
+		{{}},
+		{{Command: []string{"cat"}, Direction: "sideways"}},
+	} {
+		if _, err := newTransformHandler(&fakeMCPServer{}, transforms, nil); err == nil {
+			t.Errorf("Expected %+v to be rejected", transforms)
+		}
+	}
+}
+
+func TestTransformHandlerHidesCredentials(t *testing.T) {
+	t.Setenv("MCP_BRIDGE_KEY", "secret-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret-aws")
+	t.Setenv("TRANSFORM_LOCALE", "de")
+	remote := &fakeMCPServer{name: "remote", tools: []map[string]any{{"name": "search"}}}
+	h, err := newTransformHandler(remote, []Transform{{
+		// Report the environment in place of the result text
+		Command: []string{"sh", "-c", `printf '{"jsonrpc":"2.0","result":{"content":[{"type":"text","text":"%s"}]}}' ` +
+			`"key=$MCP_BRIDGE_KEY aws=$AWS_SECRET_ACCESS_KEY locale=$TRANSFORM_LOCALE direction=$MCP_BRIDGE_DIRECTION"`},
+		Direction: TransformResponses,
+	}}, t.Logf)
+	if err != nil {
+		t.Fatalf("newTransformHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}}`)
+	env := responses[0]["result"].(map[string]any)["content"].([]any)[0].(map[string]any)["text"].(string)
+	if strings.Contains(env, "secret-") {
+		t.Errorf("Expected credentials to be removed from the filter environment, got %s", env)
+	}
+	if !strings.Contains(env, "locale=de") || !strings.Contains(env, "direction=response") {
+		t.Errorf("Expected other variables to be passed on, got %s", env)
+	}
+}
@@ -0,0 +1,45 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// unixHost is the placeholder host used in HTTP requests sent over a Unix socket
+const unixHost = "unix"
+
+// parseUnixEndpoint splits a unix:// remote URL into the socket to dial and the
+// HTTP endpoint to request over it. An optional HTTP path follows the socket
+// path after a colon, as in nginx: "unix:///run/mcp.sock:/mcp" dials
+// /run/mcp.sock and requests http://unix/mcp.
+func parseUnixEndpoint(u *url.URL) (socketPath, endpoint string, err error) {
+	if u.Host != "" {
+		return "", "", errors.New("unix socket URL must use an absolute path (unix:///path/to/sock)")
+	}
+
+	socketPath, httpPath, _ := strings.Cut(u.Path, ":")
+	if socketPath == "" {
+		return "", "", errors.New("unix socket URL is missing the socket path")
+	}
+	if httpPath != "" && !strings.HasPrefix(httpPath, "/") {
+		httpPath = "/" + httpPath
+	}
+
+	endpoint = "http://" + unixHost + httpPath
+	if u.RawQuery != "" {
+		endpoint += "?" + u.RawQuery
+	}
+	return socketPath, endpoint, nil
+}
+
+// unixDialer returns a DialContext function that ignores the requested address
+// and always connects to socketPath
+func unixDialer(socketPath string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return d.DialContext(ctx, "unix", socketPath)
+	}
+}
@@ -0,0 +1,84 @@
+package bridge
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseUnixEndpoint(t *testing.T) {
+	tests := []struct {
+		raw      string
+		socket   string
+		endpoint string
+	}{
+		{"unix:///run/mcp.sock", "/run/mcp.sock", "http://unix"},
+		{"unix:///run/mcp.sock:/mcp", "/run/mcp.sock", "http://unix/mcp"},
+		{"unix:///run/mcp.sock:/mcp?tenant=a", "/run/mcp.sock", "http://unix/mcp?tenant=a"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.raw)
+		socket, endpoint, err := parseUnixEndpoint(u)
+		if err != nil {
+			t.Fatalf("parseUnixEndpoint(%q) failed: %v", tt.raw, err)
+		}
+		if socket != tt.socket || endpoint != tt.endpoint {
+			t.Errorf("parseUnixEndpoint(%q) = %q, %q; want %q, %q", tt.raw, socket, endpoint, tt.socket, tt.endpoint)
+		}
+	}
+
+	for _, raw := range []string{"unix://host/run/mcp.sock", "unix://"} {
+		u, _ := url.Parse(raw)
+		if _, _, err := parseUnixEndpoint(u); err == nil {
+			t.Errorf("Expected error for %q", raw)
+		}
+	}
+}
+
+func TestUnixSocketClient(t *testing.T) {
+	// Keep the socket path short; sun_path is limited to ~104 bytes
+	dir, err := os.MkdirTemp("", "mcp")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "mcp.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("Unix sockets unavailable: %v", err)
+	}
+	var gotPath string
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	})}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	u, _ := url.Parse("unix://" + socketPath + ":/mcp")
+	socket, endpoint, err := parseUnixEndpoint(u)
+	if err != nil {
+		t.Fatalf("parseUnixEndpoint failed: %v", err)
+	}
+
+	b := New(u.String(), "", false)
+	b.Proxy = ProxyOptions{URL: "http://proxy.invalid:3128"}
+	client, err := b.httpClient(socket)
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+
+	resp, err := client.Post(endpoint, "application/json", nil)
+	if err != nil {
+		t.Fatalf("Request over Unix socket failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if gotPath != "/mcp" {
+		t.Errorf("Expected request path /mcp, got %q", gotPath)
+	}
+}
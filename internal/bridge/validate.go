@@ -0,0 +1,181 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+// What to do when a tool's structuredContent does not match its outputSchema
+const (
+	OutputValidationOff      = ""         // Don't check
+	OutputValidationLog      = "log"      // Log the mismatch
+	OutputValidationAnnotate = "annotate" // Add the errors to the result's _meta
+	OutputValidationError    = "error"    // Replace the result with an error result
+)
+
+// OutputErrorsMeta is the _meta key under which annotate mode reports
+// outputSchema mismatches
+const OutputErrorsMeta = "mcp-bridge/outputSchemaErrors"
+
+// schemaHandler validates tool calls against the schemas the remote server
+// published in tools/list. Calls to tools the client has not listed yet are
+// forwarded unchecked rather than costing an extra round trip.
+type schemaHandler struct {
+	next         Handler
+	validateArgs bool
+	outputMode   string
+	logf         func(format string, v ...interface{})
+	logger       *slog.Logger // Output mismatches in log mode; default: slog.Default()
+
+	mu      sync.Mutex
+	inputs  map[string]*toolSchema // By tool name as the client sees it
+	outputs map[string]*toolSchema
+}
+
+func newSchemaHandler(next Handler, validateArgs bool, outputMode string, logger *slog.Logger, logf func(string, ...interface{})) (Handler, error) {
+	switch outputMode {
+	case OutputValidationOff, OutputValidationLog, OutputValidationAnnotate, OutputValidationError:
+	default:
+		return nil, fmt.Errorf("unsupported output validation mode: %s", outputMode)
+	}
+	if !validateArgs && outputMode == OutputValidationOff {
+		return next, nil
+	}
+	return &schemaHandler{
+		next:         next,
+		validateArgs: validateArgs,
+		outputMode:   outputMode,
+		logf:         logf,
+		logger:       logger,
+		inputs:       map[string]*toolSchema{},
+		outputs:      map[string]*toolSchema{},
+	}, nil
+}
+
+// Handle implements Handler
+func (h *schemaHandler) Handle(ctx context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+	switch req.Method {
+	case "tools/list":
+		resp, err := h.next.Handle(ctx, req)
+		if err == nil {
+			h.learn(req, resp)
+		}
+		return resp, err
+
+	case "tools/call":
+		params, err := decodeParams(req)
+		if err != nil {
+			return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, err.Error(), nil), nil
+		}
+		name, _ := params["name"].(string)
+		h.mu.Lock()
+		input, output := h.inputs[name], h.outputs[name]
+		h.mu.Unlock()
+
+		if input != nil && h.validateArgs {
+			args, ok := params["arguments"]
+			if !ok {
+				args = map[string]any{}
+			}
+			if errs := input.validate(args); len(errs) > 0 {
+				msg := fmt.Sprintf("Invalid arguments for tool %s: %s", name, joinSchemaErrors(errs))
+				return jsonrpc.NewError(req.ID, jsonrpc.InvalidParams, msg, map[string]any{"errors": errs}), nil
+			}
+		}
+
+		resp, err := h.next.Handle(ctx, req)
+		if err == nil && output != nil && h.outputMode != OutputValidationOff {
+			editResult(resp, func(result map[string]any) { h.checkOutput(name, output, result) })
+		}
+		return resp, err
+	}
+	return h.next.Handle(ctx, req)
+}
+
+// checkOutput validates a CallToolResult's structuredContent and applies the
+// output validation mode to result. Error results and results the bridge
+// shortened (see ShortenedMeta) are not checked.
+func (h *schemaHandler) checkOutput(name string, output *toolSchema, result map[string]any) {
+	if isError, _ := result["isError"].(bool); isError {
+		return
+	}
+	if meta, _ := result["_meta"].(map[string]any); meta[ShortenedMeta] == true {
+		return
+	}
+	structured, ok := result["structuredContent"]
+	var errs []schemaError
+	if !ok {
+		errs = []schemaError{{Message: "structuredContent is missing"}}
+	} else {
+		errs = output.validate(structured)
+	}
+	if len(errs) == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("Tool %s returned output that does not match its outputSchema: %s", name, joinSchemaErrors(errs))
+	switch h.outputMode {
+	case OutputValidationLog:
+		defaultLogger(h.logger).Warn(msg, "tool", name)
+	case OutputValidationAnnotate:
+		meta, _ := result["_meta"].(map[string]any)
+		if meta == nil {
+			meta = map[string]any{}
+		}
+		meta[OutputErrorsMeta] = errs
+		result["_meta"] = meta
+	case OutputValidationError:
+		for key := range result {
+			delete(result, key)
+		}
+		result["isError"] = true
+		result["content"] = []any{map[string]any{"type": "text", "text": msg}}
+	}
+}
+
+// learn records the input and output schemas from a page of tools/list. The first page
+// replaces everything learned before, so removed tools are forgotten.
+func (h *schemaHandler) learn(req *jsonrpc.Request, resp *jsonrpc.Response) {
+	if resp == nil || resp.Error != nil {
+		return
+	}
+	var result struct {
+		Tools []struct {
+			Name         string          `json:"name"`
+			InputSchema  json.RawMessage `json:"inputSchema"`
+			OutputSchema json.RawMessage `json:"outputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return
+	}
+	params, _ := decodeParams(req)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, paged := params["cursor"]; !paged {
+		h.inputs = map[string]*toolSchema{}
+		h.outputs = map[string]*toolSchema{}
+	}
+	for _, tool := range result.Tools {
+		if len(tool.InputSchema) > 0 && h.validateArgs {
+			if input, err := compileSchema(tool.InputSchema); err != nil {
+				h.logf("Not validating arguments of tool %s: %v", tool.Name, err)
+			} else {
+				h.inputs[tool.Name] = input
+			}
+		}
+		if len(tool.OutputSchema) > 0 && h.outputMode != OutputValidationOff {
+			if output, err := compileSchema(tool.OutputSchema); err != nil {
+				h.logf("Not validating output of tool %s: %v", tool.Name, err)
+			} else {
+				h.outputs[tool.Name] = output
+			}
+		}
+	}
+}
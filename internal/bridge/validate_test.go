@@ -0,0 +1,188 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-bridge/internal/bridge/jsonrpc"
+)
+
+func TestSchemaHandlerValidatesArguments(t *testing.T) {
+	remote := &fakeMCPServer{
+		name: "remote",
+		tools: []map[string]any{{
+			"name": "create_issue",
+			"inputSchema": map[string]any{
+				"$schema":  "http://json-schema.org/draft-07/schema#",
+				"type":     "object",
+				"required": []any{"title", "repo"},
+				"properties": map[string]any{
+					"title":  map[string]any{"type": "string", "minLength": 1},
+					"repo":   map[string]any{"$ref": "#/$defs/repo"},
+					"labels": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				},
+				"additionalProperties": false,
+				"$defs": map[string]any{
+					"repo": map[string]any{"type": "string", "pattern": "^[^/]+/[^/]+$"},
+				},
+			},
+		}},
+	}
+	h, err := newSchemaHandler(remote, true, OutputValidationOff, nil, t.Logf)
+	if err != nil {
+		t.Fatalf("newSchemaHandler failed: %v", err)
+	}
+
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"create_issue","arguments":{"title":"","repo":"nope","labels":["ok",7],"assignee":"me"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"create_issue","arguments":{"title":"Bug","repo":"acme/app","labels":["bug"]}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"create_issue"}}`,
+	)
+
+	errObj, ok := responses[1]["error"].(map[string]any)
+	if !ok || errObj["code"] != float64(jsonrpc.InvalidParams) {
+		t.Fatalf("Expected InvalidParams for bad arguments, got %v", responses[1])
+	}
+	var pointers []string
+	for _, e := range errObj["data"].(map[string]any)["errors"].([]any) {
+		pointers = append(pointers, e.(map[string]any)["pointer"].(string))
+	}
+	if got := strings.Join(pointers, ","); got != "/assignee,/labels/1,/repo,/title" {
+		t.Errorf("Expected every failing pointer, got %s (%v)", got, errObj["message"])
+	}
+
+	if responses[2]["result"] == nil {
+		t.Errorf("Expected valid call to be forwarded, got %v", responses[2])
+	}
+
+	errObj, _ = responses[3]["error"].(map[string]any)
+	if msg, _ := errObj["message"].(string); !strings.Contains(msg, "/title: required property is missing") {
+		t.Errorf("Expected missing arguments to be reported, got %v", responses[3])
+	}
+
+	var calls int
+	for _, call := range remote.calls {
+		if strings.HasPrefix(call, "tools/call") {
+			calls++
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected only the valid call to reach the remote, got %v", remote.calls)
+	}
+}
+
+func TestSchemaHandlerForwardsUnlistedTools(t *testing.T) {
+	remote := &fakeMCPServer{name: "remote"}
+	h, _ := newSchemaHandler(remote, true, OutputValidationOff, nil, t.Logf)
+	responses := runStdio(t, h,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"anything","arguments":{"x":1}}}`,
+	)
+	if responses[0]["result"] == nil {
+		t.Errorf("Expected call to an unlisted tool to be forwarded, got %v", responses[0])
+	}
+}
+
+func TestSchemaHandlerValidatesOutput(t *testing.T) {
+	weather := map[string]any{
+		"name": "weather",
+		"outputSchema": map[string]any{
+			"type":       "object",
+			"required":   []any{"celsius"},
+			"properties": map[string]any{"celsius": map[string]any{"type": "number"}},
+		},
+	}
+	remote := HandlerFunc(func(_ context.Context, req *jsonrpc.Request) (*jsonrpc.Response, error) {
+		if req.Method == "tools/list" {
+			return newResult(req.ID, map[string]any{"tools": []any{weather}})
+		}
+		return newResult(req.ID, map[string]any{
+			"content":           []any{map[string]any{"type": "text", "text": "warm"}},
+			"structuredContent": map[string]any{"celsius": "warm"},
+		})
+	})
+	list := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+	call := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"weather"}}`
+
+	t.Run("annotate", func(t *testing.T) {
+		h, _ := newSchemaHandler(remote, false, OutputValidationAnnotate, nil, t.Logf)
+		result := runStdio(t, h, list, call)[1]["result"].(map[string]any)
+		errs, ok := result["_meta"].(map[string]any)[OutputErrorsMeta].([]any)
+		if !ok || errs[0].(map[string]any)["pointer"] != "/celsius" {
+			t.Errorf("Expected mismatch in _meta, got %v", result)
+		}
+		if result["structuredContent"] == nil {
+			t.Error("Expected structuredContent to be kept in annotate mode")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		h, _ := newSchemaHandler(remote, false, OutputValidationError, nil, t.Logf)
+		result := runStdio(t, h, list, call)[1]["result"].(map[string]any)
+		text := result["content"].([]any)[0].(map[string]any)["text"].(string)
+		if result["isError"] != true || result["structuredContent"] != nil || !strings.Contains(text, "/celsius") {
+			t.Errorf("Expected an error result naming the pointer, got %v", result)
+		}
+	})
+
+	t.Run("log", func(t *testing.T) {
+		h, _ := newSchemaHandler(remote, false, OutputValidationLog, nil, t.Logf)
+		result := runStdio(t, h, list, call)[1]["result"].(map[string]any)
+		if result["_meta"] != nil || result["isError"] != nil {
+			t.Errorf("Expected result to pass through unchanged, got %v", result)
+		}
+	})
+
+	if _, err := newSchemaHandler(remote, false, "strict", nil, t.Logf); err == nil {
+		t.Error("Expected error for unknown output validation mode")
+	}
+}
+
+func TestSchemaHandlerSkipsShortenedOutput(t *testing.T) {
+	big := strings.Repeat("row\n", 5000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req jsonrpc.Request
+		json.NewDecoder(r.Body).Decode(&req)
+		result := map[string]any{"tools": []any{map[string]any{
+			"name":         "dump",
+			"inputSchema":  map[string]any{"type": "object"},
+			"outputSchema": map[string]any{"type": "object", "required": []any{"rows"}},
+		}}}
+		if req.Method == "tools/call" {
+			result = map[string]any{
+				"content":           []any{map[string]any{"type": "text", "text": big}},
+				"structuredContent": map[string]any{"rows": 5000},
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	defer server.Close()
+
+	truncated := New(server.URL, "", false)
+	truncated.OutputValidation = OutputValidationError
+	truncated.MaxResponseSize, truncated.TruncateResponses = 4096, true
+	offloaded := New(server.URL, "", false)
+	offloaded.OutputValidation = OutputValidationError
+	offloaded.OffloadThreshold = 4096
+
+	for name, b := range map[string]*MCPBridge{"truncated": truncated, "offloaded": offloaded} {
+		h, err := b.postHandler()
+		if err != nil {
+			t.Fatalf("%s: postHandler failed: %v", name, err)
+		}
+		responses := runStdio(t, h,
+			`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+			`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"dump"}}`,
+		)
+		result := responses[1]["result"].(map[string]any)
+		text := result["content"].([]any)[0].(map[string]any)["text"].(string)
+		if result["isError"] == true || !strings.Contains(text, "[Truncated:") {
+			t.Errorf("%s: expected the shortened result to pass output validation, got %v", name, result)
+		}
+	}
+}
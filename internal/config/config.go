@@ -0,0 +1,460 @@
+// Package config loads declarative bridge configuration from YAML or JSON
+// files and layers environment variables over it.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"mcp-bridge/internal/bridge"
+	"mcp-bridge/internal/logfile"
+)
+
+// EnvPrefix prefixes the environment variable for each command line flag,
+// e.g. MCP_BRIDGE_KEY for -key and MCP_BRIDGE_TLS_CA for -tls-ca
+const EnvPrefix = "MCP_BRIDGE_"
+
+// Config describes one or more remote servers and how the bridge logs
+type Config struct {
+	Servers []Server `yaml:"servers,omitempty"`
+	Logging Logging  `yaml:"logging,omitempty"`
+}
+
+// Server describes one remote MCP server
+type Server struct {
+	Name      string            `yaml:"name,omitempty"`
+	URL       string            `yaml:"url,omitempty"`
+	Failover  []string          `yaml:"failover,omitempty"`  // Tried in order when url fails
+	Transport string            `yaml:"transport,omitempty"` // auto, streaming or post
+	Lazy      bool              `yaml:"lazy,omitempty"`      // Connect on first use
+	Auth      Auth              `yaml:"auth,omitempty"`
+	Headers   map[string]string `yaml:"headers,omitempty"`
+	Timeouts  Timeouts          `yaml:"timeouts,omitempty"`
+	TLS       TLS               `yaml:"tls,omitempty"`
+	Proxy     Proxy             `yaml:"proxy,omitempty"`
+	Tools     Tools             `yaml:"tools,omitempty"`
+	Prompts   Filter            `yaml:"prompts,omitempty"`
+	Resources Filter            `yaml:"resources,omitempty"` // Matched against URIs
+	Policy    []PolicyRule      `yaml:"policy,omitempty"`    // Evaluated in order
+	Limits    Limits            `yaml:"limits,omitempty"`
+
+	RateLimits RateLimits  `yaml:"rate_limits,omitempty"`
+	Transforms []Transform `yaml:"transforms,omitempty"` // Applied in order
+}
+
+// RateLimit mirrors bridge.RateLimit
+type RateLimit struct {
+	Rate  string `yaml:"rate,omitempty"` // count/period, e.g. 10/min
+	Burst int    `yaml:"burst,omitempty"`
+}
+
+// RateLimits mirrors bridge.RateLimits; the inline rate is the global limit
+type RateLimits struct {
+	RateLimit `yaml:",inline"`
+	Methods   map[string]RateLimit `yaml:"methods,omitempty"`
+	Tools     map[string]RateLimit `yaml:"tools,omitempty"`
+	MaxWait   time.Duration        `yaml:"max_wait,omitempty"`
+}
+
+// Limits caps message sizes, see bridge.MCPBridge
+type Limits struct {
+	MaxRequest  ByteSize `yaml:"max_request,omitempty"`
+	MaxResponse ByteSize `yaml:"max_response,omitempty"`
+	Truncate    bool     `yaml:"truncate,omitempty"`     // Cut oversized tool results short
+	Offload     ByteSize `yaml:"offload,omitempty"`      // Keep larger tool results in the bridge
+	OffloadPage ByteSize `yaml:"offload_page,omitempty"` // Page size for reading them back
+}
+
+// PolicyRule mirrors bridge.PolicyRule
+type PolicyRule struct {
+	Name   string `yaml:"name,omitempty"`
+	When   string `yaml:"when,omitempty"`
+	Action string `yaml:"action,omitempty"` // allow, deny or rate_limit
+	Reason string `yaml:"reason,omitempty"`
+	Limit  string `yaml:"limit,omitempty"` // count/period, e.g. 10/min
+}
+
+// Transform mirrors bridge.Transform
+type Transform struct {
+	Command   []string      `yaml:"command,omitempty"`
+	Direction string        `yaml:"direction,omitempty"` // request, response or both
+	Methods   []string      `yaml:"methods,omitempty"`   // Glob patterns
+	Timeout   time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Filter lists glob patterns of visible and hidden names, see bridge.Filter
+type Filter struct {
+	Allow []string `yaml:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+}
+
+// Tools controls which remote tools are exposed
+type Tools struct {
+	Filter    `yaml:",inline"`
+	Overrides map[string]ToolOverride `yaml:"overrides,omitempty"` // By remote tool name
+
+	ValidateArguments bool   `yaml:"validate_arguments,omitempty"`
+	ValidateOutput    string `yaml:"validate_output,omitempty"` // log, annotate or error
+
+	ReadOnly      bool     `yaml:"read_only,omitempty"`       // Only tools annotated readOnlyHint
+	ReadOnlyAllow []string `yaml:"read_only_allow,omitempty"` // Trusted tools without annotations
+
+	Approval Approval `yaml:"approval,omitempty"`
+}
+
+// Approval mirrors bridge.Approval
+type Approval struct {
+	Tools       []string      `yaml:"tools,omitempty"`
+	NotReadOnly bool          `yaml:"not_read_only,omitempty"`
+	Command     []string      `yaml:"command,omitempty"`
+	URL         string        `yaml:"url,omitempty"`
+	Timeout     time.Duration `yaml:"timeout,omitempty"`
+}
+
+// ToolOverride mirrors bridge.ToolOverride
+type ToolOverride struct {
+	Name        string         `yaml:"name,omitempty"`
+	Title       string         `yaml:"title,omitempty"`
+	Description string         `yaml:"description,omitempty"`
+	Annotations map[string]any `yaml:"annotations,omitempty"`
+	InputSchema map[string]any `yaml:"input_schema,omitempty"`
+	Defaults    map[string]any `yaml:"defaults,omitempty"` // Filled in when the client omits them
+	Forced      map[string]any `yaml:"forced,omitempty"`   // Override the client and are hidden from it
+}
+
+// Auth holds API keys and optional request signing
+type Auth struct {
+	Keys        []string      `yaml:"keys,omitempty"`
+	KeyCooldown time.Duration `yaml:"key_cooldown,omitempty"`
+	Sign        *Sign         `yaml:"sign,omitempty"`
+}
+
+// Sign selects and configures request signing
+type Sign struct {
+	Mode      string `yaml:"mode,omitempty"` // hmac or sigv4
+	Secret    string `yaml:"secret,omitempty"`
+	Algorithm string `yaml:"algorithm,omitempty"`
+	Header    string `yaml:"header,omitempty"`
+	Canonical string `yaml:"canonical,omitempty"`
+	Encoding  string `yaml:"encoding,omitempty"`
+	Prefix    string `yaml:"prefix,omitempty"`
+	Region    string `yaml:"region,omitempty"`
+	Service   string `yaml:"service,omitempty"`
+	Profile   string `yaml:"profile,omitempty"`
+}
+
+// Timeouts bounds connection setup and individual requests
+type Timeouts struct {
+	Connect     time.Duration `yaml:"connect,omitempty"`
+	Request     time.Duration `yaml:"request,omitempty"`
+	HealthCheck time.Duration `yaml:"health_check,omitempty"` // Fail-back probe interval
+}
+
+// TLS mirrors bridge.TLSOptions
+type TLS struct {
+	Cert       string   `yaml:"cert,omitempty"`
+	Key        string   `yaml:"key,omitempty"`
+	CA         []string `yaml:"ca,omitempty"`
+	MinVersion string   `yaml:"min_version,omitempty"`
+	ServerName string   `yaml:"server_name,omitempty"`
+	Pins       []string `yaml:"pins,omitempty"`
+}
+
+// Proxy mirrors bridge.ProxyOptions
+type Proxy struct {
+	URL      string `yaml:"url,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	NoProxy  string `yaml:"no_proxy,omitempty"`
+}
+
+// Logging selects which debug output is enabled and how it is written
+type Logging struct {
+	Debug       bool   `yaml:"debug,omitempty"`
+	DebugClient bool   `yaml:"debug_client,omitempty"`
+	DebugServer bool   `yaml:"debug_server,omitempty"`
+	Level       string `yaml:"level,omitempty"`  // debug, info, warn or error; default debug with a debug flag, else info
+	Format      string `yaml:"format,omitempty"` // text or json
+
+	File     string        `yaml:"file,omitempty"`      // Instead of stderr
+	MaxSize  ByteSize      `yaml:"max_size,omitempty"`  // Rotate past this size (default 10MB)
+	MaxAge   time.Duration `yaml:"max_age,omitempty"`   // Rotate once the file is this old
+	MaxFiles int           `yaml:"max_files,omitempty"` // Rotated files to keep (default 5)
+
+	Redact Redact `yaml:"redact,omitempty"`
+}
+
+// Logger creates the logger described by l, writing to w
+func (l Logging) Logger(w io.Writer) (*slog.Logger, error) {
+	level := l.Level
+	if level == "" && (l.Debug || l.DebugClient || l.DebugServer) {
+		level = "debug"
+	}
+	return bridge.NewLogger(w, l.Format, level)
+}
+
+// Output opens the log file, or returns stderr when none is configured
+func (l Logging) Output() (io.WriteCloser, error) {
+	if l.File == "" {
+		return os.Stderr, nil
+	}
+	return logfile.Open(l.File, logfile.Options{
+		MaxSize:  int64(l.MaxSize),
+		MaxAge:   l.MaxAge,
+		MaxFiles: l.MaxFiles,
+	})
+}
+
+// Redact mirrors bridge.Redaction
+type Redact struct {
+	Paths    []string `yaml:"paths,omitempty"`    // e.g. params.arguments.password
+	Patterns []string `yaml:"patterns,omitempty"` // Regular expressions
+}
+
+// Load reads a YAML or JSON configuration file, expanding ${VAR} and
+// ${VAR:-default} references to environment variables in string values
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes configuration from YAML or JSON
+func Parse(data []byte) (*Config, error) {
+	var cfg expandedConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return (*Config)(&cfg), nil
+}
+
+// expandedConfig decodes a Config after expanding environment references in
+// the document. Expanding from UnmarshalYAML keeps it within the decoder, so
+// unknown keys are rejected and errors point at the lines of the file.
+type expandedConfig Config
+
+// UnmarshalYAML implements the yaml.v3 obsolete Unmarshaler, whose unmarshal
+// function decodes with the calling decoder and its KnownFields setting
+func (c *expandedConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var root nodeRef
+	if err := unmarshal(&root); err != nil {
+		return err
+	}
+	if err := expandNode(root.node); err != nil {
+		return err
+	}
+	return unmarshal((*Config)(c))
+}
+
+// nodeRef captures the node the decoder is reading, so it can be rewritten
+// in place
+type nodeRef struct{ node *yaml.Node }
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (r *nodeRef) UnmarshalYAML(node *yaml.Node) error {
+	r.node = node
+	return nil
+}
+
+var envRef = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Expand replaces ${VAR} and ${VAR:-default} with environment values; $$ is a
+// literal dollar sign. Unset variables without a default are an error.
+func Expand(s string) (string, error) {
+	var missing []string
+	out := envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		m := envRef.FindStringSubmatch(ref)
+		if value, ok := os.LookupEnv(m[1]); ok && value != "" {
+			return value
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		if _, ok := os.LookupEnv(m[1]); !ok {
+			missing = append(missing, m[1])
+		}
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+func expandNode(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		value, err := Expand(n.Value)
+		if err != nil {
+			return fmt.Errorf("config line %d: %w", n.Line, err)
+		}
+		if value != n.Value {
+			// Let the expanded text resolve like a plain scalar, so a
+			// reference can fill a bool, number or size. Quoted scalars
+			// stay strings.
+			n.Value, n.Tag = value, ""
+		}
+	}
+	for _, child := range n.Content {
+		if err := expandNode(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyEnv sets every flag in fs that was not given on the command line from
+// its MCP_BRIDGE_* environment variable, if present
+func ApplyEnv(fs *flag.FlagSet, lookup func(string) (string, bool)) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] {
+			return
+		}
+		name := EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := lookup(name); ok {
+			if err := fs.Set(f.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Bridge creates a bridge for the server with the given logging settings
+func (s Server) Bridge(logging Logging) (*bridge.MCPBridge, error) {
+	if s.URL == "" {
+		return nil, fmt.Errorf("server %q has no url", s.Name)
+	}
+
+	b := bridge.New(s.URL, strings.Join(s.Auth.Keys, ","), logging.Debug)
+	b.Name = s.Name
+	b.Redact = bridge.Redaction(logging.Redact)
+	b.SetDebugFlags(logging.DebugClient, logging.DebugServer)
+	b.KeyCooldown = s.Auth.KeyCooldown
+	b.Transport = s.Transport
+	b.Headers = s.Headers
+	b.ConnectTimeout = s.Timeouts.Connect
+	b.RequestTimeout = s.Timeouts.Request
+	b.FailoverURLs = s.Failover
+	b.Lazy = s.Lazy
+	b.ToolFilter = bridge.Filter(s.Tools.Filter)
+	b.PromptFilter = bridge.Filter(s.Prompts)
+	b.ValidateArguments = s.Tools.ValidateArguments
+	b.OutputValidation = s.Tools.ValidateOutput
+	b.ReadOnly = s.Tools.ReadOnly
+	b.ReadOnlyAllow = s.Tools.ReadOnlyAllow
+	b.Approval = bridge.Approval(s.Tools.Approval)
+	if len(s.Tools.Overrides) > 0 {
+		b.ToolOverrides = make(map[string]bridge.ToolOverride, len(s.Tools.Overrides))
+		for name, o := range s.Tools.Overrides {
+			b.ToolOverrides[name] = bridge.ToolOverride(o)
+		}
+	}
+	b.ResourceFilter = bridge.Filter(s.Resources)
+	for _, rule := range s.Policy {
+		b.Policy = append(b.Policy, bridge.PolicyRule(rule))
+	}
+	for _, t := range s.Transforms {
+		b.Transforms = append(b.Transforms, bridge.Transform(t))
+	}
+	b.HealthCheckInterval = s.Timeouts.HealthCheck
+	b.MaxRequestSize = int64(s.Limits.MaxRequest)
+	b.MaxResponseSize = int64(s.Limits.MaxResponse)
+	b.TruncateResponses = s.Limits.Truncate
+	b.OffloadThreshold = int64(s.Limits.Offload)
+	b.OffloadPageSize = int64(s.Limits.OffloadPage)
+	b.RateLimits = bridge.RateLimits{
+		Global:  bridge.RateLimit(s.RateLimits.RateLimit),
+		Methods: rateLimitMap(s.RateLimits.Methods),
+		Tools:   rateLimitMap(s.RateLimits.Tools),
+		MaxWait: s.RateLimits.MaxWait,
+	}
+	b.TLS = bridge.TLSOptions{
+		CertFile:   s.TLS.Cert,
+		KeyFile:    s.TLS.Key,
+		CAFiles:    s.TLS.CA,
+		MinVersion: s.TLS.MinVersion,
+		ServerName: s.TLS.ServerName,
+		Pins:       s.TLS.Pins,
+	}
+	b.Proxy = bridge.ProxyOptions{
+		URL:      s.Proxy.URL,
+		Username: s.Proxy.Username,
+		Password: s.Proxy.Password,
+		NoProxy:  s.Proxy.NoProxy,
+	}
+
+	signer, err := s.Auth.Sign.Signer()
+	if err != nil {
+		return nil, fmt.Errorf("server %q: %w", s.Name, err)
+	}
+	b.Signer = signer
+	return b, nil
+}
+
+// rateLimitMap converts per-method or per-tool limits
+func rateLimitMap(limits map[string]RateLimit) map[string]bridge.RateLimit {
+	if len(limits) == 0 {
+		return nil
+	}
+	m := make(map[string]bridge.RateLimit, len(limits))
+	for name, limit := range limits {
+		m[name] = bridge.RateLimit(limit)
+	}
+	return m
+}
+
+// Signer builds the request signer, or nil when signing is not configured
+func (s *Sign) Signer() (bridge.Signer, error) {
+	if s == nil {
+		return nil, nil
+	}
+	switch s.Mode {
+	case "":
+		return nil, nil
+	case "hmac":
+		if s.Secret == "" {
+			return nil, errors.New("hmac signing requires a secret")
+		}
+		return &bridge.HMACSigner{
+			Secret:    []byte(s.Secret),
+			Algorithm: s.Algorithm,
+			Header:    s.Header,
+			Canonical: s.Canonical,
+			Encoding:  s.Encoding,
+			Prefix:    s.Prefix,
+		}, nil
+	case "sigv4":
+		creds, err := bridge.LoadAWSCredentials(s.Profile)
+		if err != nil {
+			return nil, err
+		}
+		service := s.Service
+		if service == "" {
+			service = "execute-api"
+		}
+		return &bridge.SigV4Signer{Credentials: creds, Region: s.Region, Service: service}, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing mode: %s", s.Mode)
+	}
+}
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadYAML(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "gh-secret")
+	t.Setenv("EMPTY", "")
+
+	path := filepath.Join(t.TempDir(), "bridge.yaml")
+	os.WriteFile(path, []byte(`
+logging:
+  debug_client: true
+  format: json
+  file: /var/log/mcp-bridge/bridge.log
+  max_size: 5MB
+  max_age: 24h
+servers:
+  - name: github
+    url: https://mcp.github.example.com
+    failover: [https://mcp-eu.github.example.com]
+    transport: post
+    auth:
+      keys: ["${GITHUB_TOKEN}", "${BACKUP_TOKEN:-fallback}"]
+      key_cooldown: 5m
+    headers:
+      X-Tenant: acme
+      X-Empty: "${EMPTY}"
+      X-Price: "$$5"
+    timeouts:
+      connect: 2s
+      request: 30s
+      health_check: 10s
+    tls:
+      ca: [/etc/ssl/internal.pem]
+      min_version: "1.2"
+    proxy:
+      url: socks5://proxy.corp:1080
+      no_proxy: .corp.example
+    tools:
+      deny: ["admin_*"]
+      overrides:
+        search:
+          name: gh_search
+          input_schema:
+            properties:
+              query: {description: "GitHub search syntax"}
+              legacy: null
+          defaults: {per_page: 20}
+          forced: {org: acme}
+      read_only: true
+      read_only_allow: [search]
+      approval:
+        not_read_only: true
+        command: [confirm, --gui]
+        timeout: 30s
+    resources:
+      allow: ["repo://*"]
+    rate_limits:
+      rate: 20/s
+      max_wait: 5s
+      tools:
+        search: {rate: 10/min, burst: 3}
+    transforms:
+      - command: [scrub-pii, --strict]
+        direction: request
+        methods: ["tools/call"]
+    limits:
+      max_request: 512KB
+      max_response: 1048576
+      truncate: true
+      offload: 100KB
+    policy:
+      - name: search
+        when: tool == "search"
+        action: rate_limit
+        limit: 10/min
+  - name: docs
+    url: unix:///run/docs.sock:/mcp
+`), 0o600)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if logger, err := cfg.Logging.Logger(io.Discard); err != nil || !logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("Expected a debug flag to imply debug level, got %v", err)
+	}
+	if cfg.Logging.MaxSize != 5<<20 || cfg.Logging.MaxAge != 24*time.Hour {
+		t.Errorf("Expected log rotation settings, got %+v", cfg.Logging)
+	}
+	if len(cfg.Servers) != 2 || !cfg.Logging.DebugClient {
+		t.Fatalf("Unexpected config: %+v", cfg)
+	}
+
+	gh := cfg.Servers[0]
+	if gh.Auth.Keys[0] != "gh-secret" || gh.Auth.Keys[1] != "fallback" {
+		t.Errorf("Expected interpolated keys, got %v", gh.Auth.Keys)
+	}
+	if gh.Headers["X-Empty"] != "" || gh.Headers["X-Price"] != "$5" {
+		t.Errorf("Unexpected headers: %v", gh.Headers)
+	}
+	if gh.Auth.KeyCooldown != 5*time.Minute || gh.Timeouts.Request != 30*time.Second {
+		t.Errorf("Expected durations to parse, got %v and %v", gh.Auth.KeyCooldown, gh.Timeouts.Request)
+	}
+
+	b, err := gh.Bridge(cfg.Logging)
+	if err != nil {
+		t.Fatalf("Bridge failed: %v", err)
+	}
+	if b.Name != "github" || b.APIKey != "gh-secret,fallback" || b.Transport != "post" {
+		t.Errorf("Unexpected bridge: %+v", b)
+	}
+	if len(b.FailoverURLs) != 1 || b.HealthCheckInterval != 10*time.Second {
+		t.Errorf("Expected failover settings to carry over, got %v every %v", b.FailoverURLs, b.HealthCheckInterval)
+	}
+	if b.ToolFilter.Deny[0] != "admin_*" || b.ResourceFilter.Allow[0] != "repo://*" {
+		t.Errorf("Expected filters to carry over, got %+v and %+v", b.ToolFilter, b.ResourceFilter)
+	}
+	search := b.ToolOverrides["search"]
+	props := search.InputSchema["properties"].(map[string]any)
+	if search.Name != "gh_search" || props["query"] == nil {
+		t.Errorf("Expected tool override to carry over, got %+v", search)
+	}
+	if search.Defaults["per_page"] != 20 || search.Forced["org"] != "acme" {
+		t.Errorf("Expected injected arguments to carry over, got %v and %v", search.Defaults, search.Forced)
+	}
+	if legacy, ok := props["legacy"]; !ok || legacy != nil {
+		t.Errorf("Expected null to survive as a removal marker, got %v", props)
+	}
+	if !b.ReadOnly || len(b.ReadOnlyAllow) != 1 {
+		t.Errorf("Expected read-only mode to carry over, got %v %v", b.ReadOnly, b.ReadOnlyAllow)
+	}
+	if !b.Approval.NotReadOnly || len(b.Approval.Command) != 2 || b.Approval.Timeout != 30*time.Second {
+		t.Errorf("Expected approval hook to carry over, got %+v", b.Approval)
+	}
+	if b.OffloadThreshold != 100<<10 || b.OffloadPageSize != 0 {
+		t.Errorf("Expected offload threshold to carry over, got %d", b.OffloadThreshold)
+	}
+	if b.MaxRequestSize != 512<<10 || b.MaxResponseSize != 1<<20 || !b.TruncateResponses {
+		t.Errorf("Expected size limits to carry over, got %d and %d", b.MaxRequestSize, b.MaxResponseSize)
+	}
+	if b.RateLimits.Global.Rate != "20/s" || b.RateLimits.Tools["search"].Burst != 3 || b.RateLimits.MaxWait != 5*time.Second {
+		t.Errorf("Expected rate limits to carry over, got %+v", b.RateLimits)
+	}
+	if len(b.Transforms) != 1 || b.Transforms[0].Command[1] != "--strict" || b.Transforms[0].Direction != "request" {
+		t.Errorf("Expected transforms to carry over, got %+v", b.Transforms)
+	}
+	if len(b.Policy) != 1 || b.Policy[0].When != `tool == "search"` || b.Policy[0].Limit != "10/min" {
+		t.Errorf("Expected policy to carry over, got %+v", b.Policy)
+	}
+	if !b.DebugClient || b.DebugServer {
+		t.Errorf("Expected client-only debug logging, got client=%v server=%v", b.DebugClient, b.DebugServer)
+	}
+	if b.TLS.MinVersion != "1.2" || b.Proxy.URL != "socks5://proxy.corp:1080" || b.ConnectTimeout != 2*time.Second {
+		t.Errorf("Expected TLS, proxy and timeouts to carry over: %+v", b)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	cfg, err := Parse([]byte(`{"servers": [{"name": "a", "url": "https://a.example.com", "auth": {"sign": {"mode": "hmac", "secret": "s"}}}]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	b, err := cfg.Servers[0].Bridge(cfg.Logging)
+	if err != nil {
+		t.Fatalf("Bridge failed: %v", err)
+	}
+	if b.Signer == nil {
+		t.Error("Expected HMAC signer from JSON config")
+	}
+}
+
+func TestParseInterpolatedTypes(t *testing.T) {
+	t.Setenv("MCP_BRIDGE_TEST_LAZY", "true")
+	t.Setenv("MCP_BRIDGE_TEST_BURST", "5")
+	t.Setenv("MCP_BRIDGE_TEST_SIZE", "2MB")
+	t.Setenv("MCP_BRIDGE_TEST_NAME", "42")
+	cfg, err := Parse([]byte(`
+servers:
+  - name: ${MCP_BRIDGE_TEST_NAME}
+    url: https://a
+    lazy: ${MCP_BRIDGE_TEST_LAZY}
+    headers: {X-Lazy: "${MCP_BRIDGE_TEST_LAZY}"}
+    limits: {max_request: "${MCP_BRIDGE_TEST_SIZE}"}
+    rate_limits:
+      rate: 10/min
+      burst: ${MCP_BRIDGE_TEST_BURST}
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	s := cfg.Servers[0]
+	if !s.Lazy {
+		t.Error("Expected ${VAR} to fill a bool")
+	}
+	if s.RateLimits.Burst != 5 {
+		t.Errorf("Expected ${VAR} to fill an int, got %d", s.RateLimits.Burst)
+	}
+	if s.Limits.MaxRequest != 2<<20 {
+		t.Errorf("Expected ${VAR} to fill a size, got %d", s.Limits.MaxRequest)
+	}
+	if s.Name != "42" || s.Headers["X-Lazy"] != "true" {
+		t.Errorf("Expected strings to stay strings, got %q and %q", s.Name, s.Headers["X-Lazy"])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"missing variable": `servers: [{url: "${MCP_BRIDGE_TEST_UNSET}"}]`,
+		"bad duration":     `servers: [{url: "https://a", timeouts: {connect: soon}}]`,
+		"unknown type":     `servers: {url: "https://a"}`,
+	}
+	for name, data := range cases {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	// A misspelled key is reported with its line rather than ignored
+	_, err := Parse([]byte("servers:\n  - url: https://a\n    read_ony: true\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3: field read_ony not found") {
+		t.Errorf("Expected the misspelled key to be rejected, got %v", err)
+	}
+
+	if _, err := (Server{Name: "x"}).Bridge(Logging{}); err == nil {
+		t.Error("Expected error for server without url")
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	valid := map[string]ByteSize{
+		"512":   512,
+		"1.5KB": 1536,
+		"10 mb": 10 << 20,
+		"2G":    2 << 30,
+	}
+	for in, want := range valid {
+		if got, err := ParseByteSize(in); err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "-1", "ten", "NaN", "Inf", "+InfMB", "1e300", "8589934592GB"} {
+		if got, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q) = %d, expected error", in, got)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	key := fs.String("key", "", "")
+	debug := fs.Bool("debug", false, "")
+	cooldown := fs.Duration("key-cooldown", time.Minute, "")
+	fs.Parse([]string{"-key", "from-flag"})
+
+	env := map[string]string{
+		"MCP_BRIDGE_KEY":          "from-env",
+		"MCP_BRIDGE_DEBUG":        "true",
+		"MCP_BRIDGE_KEY_COOLDOWN": "10s",
+	}
+	err := ApplyEnv(fs, func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+
+	if *key != "from-flag" {
+		t.Errorf("Expected command line flag to win over environment, got %q", *key)
+	}
+	if !*debug || *cooldown != 10*time.Second {
+		t.Errorf("Expected environment to set unset flags, got debug=%v cooldown=%v", *debug, *cooldown)
+	}
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("debug", false, "")
+	err = ApplyEnv(fs, func(name string) (string, bool) {
+		return "maybe", name == "MCP_BRIDGE_DEBUG"
+	})
+	if err == nil {
+		t.Error("Expected error for invalid boolean in environment")
+	}
+}
+
+func TestLoggingOutput(t *testing.T) {
+	if out, err := (Logging{}).Output(); err != nil || out != os.Stderr {
+		t.Errorf("Expected stderr without a log file, got %v (%v)", out, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "logs", "bridge.log")
+	out, err := Logging{File: path}.Output()
+	if err != nil {
+		t.Fatalf("Output failed: %v", err)
+	}
+	logger, _ := Logging{}.Logger(out)
+	logger.Info("hello")
+	out.Close()
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "msg=hello") {
+		t.Errorf("Expected the record in the log file, got %q", data)
+	}
+}
@@ -0,0 +1,493 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Layouts of the mcpServers-style JSON written by different IDEs
+const (
+	layoutMCPServers = "mcpServers"      // Claude Desktop, Cursor, Warp: {"mcpServers": {...}}
+	layoutVSCode     = "servers"         // VS Code mcp.json: {"servers": {...}}
+	layoutVSCodeUser = "mcp.servers"     // VS Code settings.json: {"mcp": {"servers": {...}}}
+	layoutZed        = "context_servers" // Zed settings.json: {"context_servers": {...}}
+	layoutFlat       = "flat"            // Bare {"name": {...}} map, as in Warp and Zed snippets
+)
+
+// ideFile is a parsed IDE configuration and the map holding its servers
+type ideFile struct {
+	root     map[string]any
+	servers  map[string]any
+	layout   string
+	comments bool // The file has comments, which rewriting would lose
+}
+
+// ErrIDEComments is returned by RewriteIDE for files with comments
+var ErrIDEComments = errors.New("the file has comments, which rewriting would remove; move remote servers to mcp-bridge by hand")
+
+// remoteEntry is a remote HTTP server found in an IDE configuration
+type remoteEntry struct {
+	url     string
+	key     string
+	headers map[string]string
+	bridged bool // Already launched through mcp-bridge
+}
+
+// ImportIDE reads a Claude Desktop, Cursor, VS Code, Zed or Warp MCP
+// configuration and returns bridge configuration for its remote HTTP servers.
+// Local stdio servers are listed in skipped.
+func ImportIDE(data []byte) (cfg *Config, skipped []string, err error) {
+	f, err := parseIDEFile(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg = &Config{}
+	for _, name := range sortedKeys(f.servers) {
+		entry, _ := f.servers[name].(map[string]any)
+		remote, ok := parseRemoteEntry(entry)
+		if !ok {
+			skipped = append(skipped, name)
+			continue
+		}
+		server := Server{Name: serverName(name), URL: remote.url, Headers: remote.headers}
+		if remote.key != "" {
+			server.Auth.Keys = []string{remote.key}
+		}
+		cfg.Servers = append(cfg.Servers, server)
+	}
+	return cfg, skipped, nil
+}
+
+// RewriteIDE rewrites remote HTTP entries in an IDE configuration so they are
+// launched through mcp-bridge, returning the new file and the rewritten names.
+// Only the rewritten entries change; the rest of the file, such as other
+// editor settings, is kept byte for byte. Files with comments are refused
+// with ErrIDEComments.
+func RewriteIDE(data []byte, command string) ([]byte, []string, error) {
+	f, err := parseIDEFile(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.comments {
+		return nil, nil, ErrIDEComments
+	}
+
+	type splice struct {
+		start, end int
+		value      []byte
+	}
+	var splices []splice
+	var rewritten []string
+	stripped, _ := stripJSONC(data)
+	for _, name := range sortedKeys(f.servers) {
+		entry, _ := f.servers[name].(map[string]any)
+		remote, ok := parseRemoteEntry(entry)
+		if !ok || remote.bridged {
+			continue
+		}
+		start, end, err := valueSpan(stripped, f.path(name))
+		if err != nil {
+			return nil, nil, err
+		}
+		indent, unit := lineIndent(data, start)
+		value, err := json.MarshalIndent(bridgeEntry(f.layout, entry, remote, command), indent, unit)
+		if err != nil {
+			return nil, nil, err
+		}
+		splices = append(splices, splice{start, end, value})
+		rewritten = append(rewritten, name)
+	}
+
+	// Splice from the end so earlier offsets stay valid
+	sort.Slice(splices, func(i, j int) bool { return splices[i].start > splices[j].start })
+	out := append([]byte(nil), data...)
+	for _, s := range splices {
+		out = append(out[:s.start:s.start], append(s.value, out[s.end:]...)...)
+	}
+	return out, rewritten, nil
+}
+
+// path returns the keys leading from the root of the file to a server entry
+func (f *ideFile) path(name string) []string {
+	switch f.layout {
+	case layoutVSCodeUser:
+		return []string{"mcp", "servers", name}
+	case layoutFlat:
+		return []string{name}
+	}
+	return []string{f.layout, name}
+}
+
+// valueSpan returns the byte range of the value at path in a JSON document
+func valueSpan(data []byte, path []string) (int, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for depth := 0; depth < len(path); depth++ {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return 0, 0, fmt.Errorf("invalid IDE configuration: %s is not an object", strings.Join(path[:depth], "."))
+		}
+		for {
+			if !dec.More() {
+				return 0, 0, fmt.Errorf("invalid IDE configuration: %s not found", strings.Join(path[:depth+1], "."))
+			}
+			tok, err := dec.Token()
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid IDE configuration: %w", err)
+			}
+			if tok == path[depth] {
+				break
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return 0, 0, fmt.Errorf("invalid IDE configuration: %w", err)
+			}
+		}
+	}
+	var value json.RawMessage
+	if err := dec.Decode(&value); err != nil {
+		return 0, 0, fmt.Errorf("invalid IDE configuration: %w", err)
+	}
+	end := int(dec.InputOffset())
+	return end - len(value), end, nil
+}
+
+// lineIndent returns the indentation of the line holding offset i and the
+// unit to indent nested lines by: a tab in tab-indented files, two spaces
+// otherwise
+func lineIndent(data []byte, i int) (string, string) {
+	start := bytes.LastIndexByte(data[:i], '\n') + 1
+	end := start
+	for end < i && (data[end] == ' ' || data[end] == '\t') {
+		end++
+	}
+	indent := string(data[start:end])
+	if strings.HasPrefix(indent, "\t") {
+		return indent, "\t"
+	}
+	return indent, "  "
+}
+
+func parseIDEFile(data []byte) (*ideFile, error) {
+	var root map[string]any
+	stripped, comments := stripJSONC(data)
+	if err := json.Unmarshal(stripped, &root); err != nil {
+		return nil, fmt.Errorf("invalid IDE configuration: %w", err)
+	}
+
+	f := &ideFile{root: root, comments: comments}
+	if servers, ok := root["mcpServers"].(map[string]any); ok {
+		f.servers, f.layout = servers, layoutMCPServers
+	} else if servers, ok := root["servers"].(map[string]any); ok {
+		f.servers, f.layout = servers, layoutVSCode
+	} else if servers, ok := root["context_servers"].(map[string]any); ok {
+		f.servers, f.layout = servers, layoutZed
+	} else if mcp, ok := root["mcp"].(map[string]any); ok {
+		if servers, ok := mcp["servers"].(map[string]any); ok {
+			f.servers, f.layout = servers, layoutVSCodeUser
+		}
+	}
+	if f.servers == nil {
+		for _, v := range root {
+			entry, ok := v.(map[string]any)
+			if !ok || (entry["command"] == nil && entry["url"] == nil) {
+				return nil, fmt.Errorf("no MCP servers found in IDE configuration")
+			}
+		}
+		f.servers, f.layout = root, layoutFlat
+	}
+	return f, nil
+}
+
+// parseRemoteEntry recognises remote HTTP servers configured by URL, through
+// mcp-remote, or already through mcp-bridge
+func parseRemoteEntry(entry map[string]any) (remoteEntry, bool) {
+	if entry == nil {
+		return remoteEntry{}, false
+	}
+
+	if url := firstString(entry, "url", "serverUrl"); url != "" {
+		switch firstString(entry, "type", "transport") {
+		case "", "http", "sse", "streamable-http", "streamableHttp":
+		default:
+			return remoteEntry{}, false
+		}
+		remote := remoteEntry{url: url, headers: map[string]string{}}
+		if headers, ok := entry["headers"].(map[string]any); ok {
+			for name, v := range headers {
+				if value, ok := v.(string); ok {
+					remote.headers[name] = value
+				}
+			}
+		}
+		remote.extractBearer()
+		return remote, true
+	}
+
+	command, args, env := entryCommand(entry)
+	if env == nil {
+		env, _ = entry["env"].(map[string]any)
+	}
+	switch base := filepath.Base(command); {
+	case base == "mcp-bridge":
+		return parseBridgeArgs(args, env)
+	case base == "npx" || base == "bunx" || base == "pnpm":
+		return parseMCPRemoteArgs(args)
+	}
+	return remoteEntry{}, false
+}
+
+// entryCommand reads the command, args and env of an entry, including Zed's
+// {"command": {"path": ..., "args": ..., "env": ...}} form
+func entryCommand(entry map[string]any) (string, []string, map[string]any) {
+	cmd := entry
+	if nested, ok := entry["command"].(map[string]any); ok {
+		cmd = nested
+	}
+	command := firstString(cmd, "command", "path")
+	var args []string
+	if list, ok := cmd["args"].([]any); ok {
+		for _, a := range list {
+			if s, ok := a.(string); ok {
+				args = append(args, s)
+			}
+		}
+	}
+	env, _ := cmd["env"].(map[string]any)
+	return command, args, env
+}
+
+// parseBridgeArgs reads the remote of an mcp-bridge entry from its flags, in
+// both the "-flag value" and "-flag=value" forms, and its API key from
+// MCP_BRIDGE_KEY in the entry's env, where RewriteIDE puts it
+func parseBridgeArgs(args []string, env map[string]any) (remoteEntry, bool) {
+	remote := remoteEntry{headers: map[string]string{}, bridged: true}
+	if key, ok := env[EnvPrefix+"KEY"].(string); ok {
+		remote.key = key
+	}
+	for i := 0; i < len(args); i++ {
+		flag, value, inline := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !inline {
+			if i+1 >= len(args) {
+				break
+			}
+			value = args[i+1]
+		}
+		switch flag {
+		case "server":
+			remote.url = value
+		case "key":
+			remote.key = envReference(value)
+		case "header":
+			name, v, _ := strings.Cut(value, ":")
+			remote.headers[strings.TrimSpace(name)] = envReference(strings.TrimSpace(v))
+		default:
+			continue
+		}
+		if !inline {
+			i++
+		}
+	}
+	if len(remote.headers) == 0 {
+		remote.headers = nil
+	}
+	return remote, remote.url != ""
+}
+
+// parseMCPRemoteArgs reads "npx mcp-remote <url> --header 'Name: value'"
+func parseMCPRemoteArgs(args []string) (remoteEntry, bool) {
+	remote := remoteEntry{headers: map[string]string{}}
+	seen := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "mcp-remote"):
+			seen = true
+		case seen && arg == "--header" && i+1 < len(args):
+			name, value, _ := strings.Cut(args[i+1], ":")
+			remote.headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+			i++
+		case seen && remote.url == "" && strings.HasPrefix(arg, "http"):
+			remote.url = arg
+		}
+	}
+	remote.extractBearer()
+	return remote, remote.url != ""
+}
+
+// extractBearer moves an "Authorization: Bearer <key>" header into key
+func (r *remoteEntry) extractBearer() {
+	for name, value := range r.headers {
+		if strings.EqualFold(name, "Authorization") {
+			if key, ok := strings.CutPrefix(value, "Bearer "); ok {
+				r.key = strings.TrimSpace(key)
+				delete(r.headers, name)
+			}
+		}
+	}
+	if len(r.headers) == 0 {
+		r.headers = nil
+	}
+}
+
+// bridgeEntry builds an entry that launches mcp-bridge for remote, in the
+// shape the IDE expects. The API key is passed through the environment so it
+// stays out of the process list.
+func bridgeEntry(layout string, old map[string]any, remote remoteEntry, command string) map[string]any {
+	args := []any{"-server", remote.url}
+	for _, name := range sortedKeys(remote.headers) {
+		args = append(args, "-header", name+": "+remote.headers[name])
+	}
+
+	_, _, env := entryCommand(old)
+	if env == nil {
+		env, _ = old["env"].(map[string]any)
+	}
+	if remote.key != "" {
+		if env == nil {
+			env = map[string]any{}
+		}
+		env[EnvPrefix+"KEY"] = remote.key
+	}
+
+	entry := map[string]any{"command": command, "args": args}
+	if env != nil {
+		entry["env"] = env
+	}
+	switch layout {
+	case layoutVSCode, layoutVSCodeUser:
+		entry["type"] = "stdio"
+	case layoutZed:
+		if _, nested := old["command"].(map[string]any); nested {
+			delete(entry, "command")
+			entry["path"] = command
+			entry = map[string]any{"command": entry}
+		} else {
+			entry["source"] = "custom"
+		}
+	}
+	return entry
+}
+
+var shellVar = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)$`)
+
+// envReference turns a shell-style "$VAR" argument into a "${VAR}" reference
+func envReference(s string) string {
+	return shellVar.ReplaceAllString(s, "$${$1}")
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9-]+`)
+
+// serverName adapts an IDE entry name to the bridge's server name rules
+func serverName(name string) string {
+	name = strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "-")
+	if name == "" || !('A' <= name[0] && name[0] <= 'Z' || 'a' <= name[0] && name[0] <= 'z') {
+		name = "server-" + name
+	}
+	return strings.TrimSuffix(name, "-")
+}
+
+func firstString(m map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := m[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stripJSONC blanks out // and /* */ comments and trailing commas, as allowed
+// in VS Code and Zed settings files, and reports whether it found comments.
+// Offsets in the result match those in data.
+func stripJSONC(data []byte) ([]byte, bool) {
+	out := make([]byte, 0, len(data))
+	inString, comments := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			comments = true
+			for ; i < len(data) && data[i] != '\n'; i++ {
+				out = append(out, ' ')
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			comments = true
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				end = len(data) - i - 2
+			} else {
+				end += 2
+			}
+			for _, b := range data[i : i+2+end] {
+				if b != '\n' {
+					b = ' '
+				}
+				out = append(out, b)
+			}
+			i += 1 + end
+		case c == ',':
+			if j := skipJSONCSpace(data, i+1); j < len(data) && (data[j] == '}' || data[j] == ']') {
+				out = append(out, ' ')
+				continue
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, comments
+}
+
+// skipJSONCSpace returns the index of the first byte at or after i that is
+// neither whitespace nor part of a comment
+func skipJSONCSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch {
+		case strings.ContainsRune(" \t\r\n", rune(data[i])):
+			i++
+		case bytes.HasPrefix(data[i:], []byte("//")):
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case bytes.HasPrefix(data[i:], []byte("/*")):
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return len(data)
+			}
+			i += end + 4
+		default:
+			return i
+		}
+	}
+	return i
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestImportIDE(t *testing.T) {
+	cases := map[string]string{
+		"cursor": `{"mcpServers": {
+			"github": {"url": "https://mcp.github.example.com", "headers": {"Authorization": "Bearer gh-key", "X-Tenant": "acme"}},
+			"local": {"command": "node", "args": ["server.js"]}
+		}}`,
+		"claude desktop via mcp-remote": `{"mcpServers": {
+			"github": {"command": "npx", "args": ["-y", "mcp-remote", "https://mcp.github.example.com", "--header", "Authorization: Bearer gh-key", "--header", "X-Tenant: acme"]},
+			"local": {"command": "uvx", "args": ["local-server"]}
+		}}`,
+		"vscode settings with comments": `{
+			// Editor settings
+			"editor.tabSize": 2,
+			"mcp": {"servers": {
+				"github": {"type": "http", "url": "https://mcp.github.example.com", "headers": {"Authorization": "Bearer gh-key", "X-Tenant": "acme"},},
+				"local": {"type": "stdio", "command": "node"}, // note
+				/* more servers go here */
+			}},
+		}`,
+		"zed": `{"context_servers": {
+			"github": {"command": {"path": "/usr/local/bin/mcp-bridge", "args": ["-server", "https://mcp.github.example.com", "-key", "gh-key", "-header", "X-Tenant: acme"]}},
+			"local": {"command": {"path": "node", "args": []}}
+		}}`,
+		"already rewritten": `{"servers": {
+			"github": {"type": "stdio", "command": "mcp-bridge", "args": ["-server=https://mcp.github.example.com", "-header=X-Tenant: acme"], "env": {"MCP_BRIDGE_KEY": "gh-key"}},
+			"local": {"type": "stdio", "command": "node"}
+		}}`,
+	}
+	for name, data := range cases {
+		cfg, skipped, err := ImportIDE([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: ImportIDE failed: %v", name, err)
+		}
+		if len(cfg.Servers) != 1 || len(skipped) != 1 || skipped[0] != "local" {
+			t.Fatalf("%s: expected one server and local skipped, got %+v, %v", name, cfg.Servers, skipped)
+		}
+		s := cfg.Servers[0]
+		if s.Name != "github" || s.URL != "https://mcp.github.example.com" {
+			t.Errorf("%s: unexpected server %+v", name, s)
+		}
+		if len(s.Auth.Keys) != 1 || s.Auth.Keys[0] != "gh-key" {
+			t.Errorf("%s: expected bearer token as key, got %v", name, s.Auth.Keys)
+		}
+		if len(s.Headers) != 1 || s.Headers["X-Tenant"] != "acme" {
+			t.Errorf("%s: unexpected headers %v", name, s.Headers)
+		}
+	}
+}
+
+func TestImportIDEWarp(t *testing.T) {
+	cfg, _, err := ImportIDE([]byte(`{"my tools!": {"command": "mcp-bridge", "args": ["--server", "https://tools.example.com", "--key", "$TOOLS_KEY"], "env": {}}}`))
+	if err != nil {
+		t.Fatalf("ImportIDE failed: %v", err)
+	}
+	s := cfg.Servers[0]
+	if s.Name != "my-tools" || s.Auth.Keys[0] != "${TOOLS_KEY}" {
+		t.Errorf("Expected sanitized name and env reference, got %+v", s)
+	}
+
+	if _, _, err := ImportIDE([]byte(`{"editor.tabSize": 2}`)); err == nil {
+		t.Error("Expected error for file without MCP servers")
+	}
+}
+
+func TestRewriteIDE(t *testing.T) {
+	data := []byte(`{
+		"servers": {
+			"github": {"type": "http", "url": "https://mcp.github.example.com", "headers": {"Authorization": "Bearer gh-key", "X-Tenant": "acme"}},
+			"bridged": {"type": "stdio", "command": "mcp-bridge", "args": ["-server", "https://other.example.com"]},
+			"local": {"type": "stdio", "command": "node"}
+		}
+	}`)
+	out, rewritten, err := RewriteIDE(data, "mcp-bridge")
+	if err != nil {
+		t.Fatalf("RewriteIDE failed: %v", err)
+	}
+	if strings.Join(rewritten, ",") != "github" {
+		t.Errorf("Expected only github to be rewritten, got %v", rewritten)
+	}
+
+	var root struct {
+		Servers map[string]struct {
+			Type    string            `json:"type"`
+			Command string            `json:"command"`
+			Args    []string          `json:"args"`
+			Env     map[string]string `json:"env"`
+		} `json:"servers"`
+	}
+	if err := json.Unmarshal(out, &root); err != nil {
+		t.Fatalf("Rewritten file is not JSON: %v", err)
+	}
+	gh := root.Servers["github"]
+	if gh.Type != "stdio" || gh.Command != "mcp-bridge" {
+		t.Errorf("Unexpected rewritten entry: %+v", gh)
+	}
+	if got := strings.Join(gh.Args, " "); got != "-server https://mcp.github.example.com -header X-Tenant: acme" {
+		t.Errorf("Unexpected args: %s", got)
+	}
+	if gh.Env["MCP_BRIDGE_KEY"] != "gh-key" {
+		t.Errorf("Expected key in environment, got %v", gh.Env)
+	}
+	if root.Servers["local"].Command != "node" {
+		t.Error("Expected local server to be left alone")
+	}
+}
+
+func TestRewriteIDERefusesComments(t *testing.T) {
+	data := []byte(`{
+		// Work servers
+		"servers": {"github": {"type": "http", "url": "https://mcp.github.example.com"}}
+	}`)
+	if _, _, err := RewriteIDE(data, "mcp-bridge"); !errors.Is(err, ErrIDEComments) {
+		t.Errorf("Expected ErrIDEComments, got %v", err)
+	}
+	// Comment markers inside strings are not comments
+	data = []byte(`{"servers": {"github": {"type": "http", "url": "https://mcp.github.example.com/*"}}}`)
+	if _, _, err := RewriteIDE(data, "mcp-bridge"); err != nil {
+		t.Errorf("RewriteIDE failed: %v", err)
+	}
+}
+
+func TestRewriteIDEKeepsOtherSettings(t *testing.T) {
+	data := []byte(`{
+	"zoom": 1.50,
+	"editor.tabSize": 2,
+	"mcp": {
+		"servers": {
+			"github": {"type": "http", "url": "https://mcp.github.example.com"},
+			"local": {"type": "stdio", "command": "node"},
+		},
+	},
+	"telemetry.id": 12345678901234567890
+}
+`)
+	out, rewritten, err := RewriteIDE(data, "mcp-bridge")
+	if err != nil {
+		t.Fatalf("RewriteIDE failed: %v", err)
+	}
+	if strings.Join(rewritten, ",") != "github" {
+		t.Errorf("Expected github to be rewritten, got %v", rewritten)
+	}
+	want := `{
+	"zoom": 1.50,
+	"editor.tabSize": 2,
+	"mcp": {
+		"servers": {
+			"github": {
+				"args": [
+					"-server",
+					"https://mcp.github.example.com"
+				],
+				"command": "mcp-bridge",
+				"type": "stdio"
+			},
+			"local": {"type": "stdio", "command": "node"},
+		},
+	},
+	"telemetry.id": 12345678901234567890
+}
+`
+	if string(out) != want {
+		t.Errorf("Expected only the github entry to change, got:\n%s", out)
+	}
+}
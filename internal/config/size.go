@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ByteSize is a size in bytes written as a plain number or with a KB, MB or
+// GB suffix (powers of 1024), e.g. 512KB or 10MB. It doubles as a flag.Value.
+type ByteSize int64
+
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+}
+
+// ParseByteSize parses a size such as 10MB
+func ParseByteSize(s string) (ByteSize, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	factor := int64(1)
+	for _, unit := range sizeUnits {
+		if number, ok := strings.CutSuffix(text, unit.suffix); ok {
+			text, factor = strings.TrimSpace(number), unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which no int64 holds
+	if n*float64(factor) >= float64(math.MaxInt64) {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return ByteSize(n * float64(factor)), nil
+}
+
+// String implements flag.Value
+func (b *ByteSize) String() string {
+	if b == nil {
+		return "0"
+	}
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+// Set implements flag.Value
+func (b *ByteSize) Set(s string) error {
+	size, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// UnmarshalYAML accepts numbers and suffixed strings
+func (b *ByteSize) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: size must be a number or a string such as 10MB", node.Line)
+	}
+	return b.Set(node.Value)
+}
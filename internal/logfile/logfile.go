@@ -0,0 +1,219 @@
+// Package logfile writes logs to a file that is rotated by size and age,
+// keeping a bounded number of rotated files next to it.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for Options fields left zero
+const (
+	DefaultMaxSize  = 10 << 20
+	DefaultMaxFiles = 5
+)
+
+// timeFormat stamps rotated files, e.g. bridge.log.20251003-174043.120. Files
+// rotated within the same millisecond get a counter: bridge.log.20251003-174043.120.1
+const timeFormat = "20060102-150405.000"
+
+// Options controls rotation
+type Options struct {
+	MaxSize  int64         // Rotate before the file grows past this many bytes (default 10MB)
+	MaxAge   time.Duration // Rotate once the file is this old (0 means never)
+	MaxFiles int           // Rotated files to keep, newest first (default 5)
+}
+
+// Writer is an io.WriteCloser over a rotating log file. It is safe for
+// concurrent use. Each process needs its own file: writers in different
+// processes never overwrite each other's rotated files, but each rotates on
+// its own count of the size, so a shared file is rotated too early and
+// writes can land in a file another process has just moved aside.
+type Writer struct {
+	path string
+	opts Options
+	now  func() time.Time
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	created time.Time
+}
+
+// Open opens path for appending, creating it and its directory as needed.
+// A file left over from an earlier run counts as created at its last
+// modification, so a stale file is rotated on the first write.
+func Open(path string, opts Options) (*Writer, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = DefaultMaxFiles
+	}
+	w := &Writer{path: path, opts: opts, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("log file: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("log file: %w", err)
+	}
+	w.file, w.size, w.created = file, info.Size(), w.now()
+	if w.size > 0 {
+		w.created = info.ModTime()
+	}
+	return nil
+}
+
+// Write implements io.Writer, rotating first when p would take the file
+// past MaxSize or the file has reached MaxAge. A single write larger than
+// MaxSize still goes to one file. When rotation fails, p still goes to the
+// current file, the rotation error is returned and the next write retries.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	tooBig := w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize
+	tooOld := w.opts.MaxAge > 0 && w.now().Sub(w.created) >= w.opts.MaxAge
+	if tooBig || tooOld {
+		rotateErr = w.rotate()
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+// Close implements io.Closer
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// rotate moves the current file aside, starts a new one and removes rotated
+// files beyond MaxFiles. The old handle stays open until the new file is, so
+// a failed rotation leaves the writer logging to the old file. Must be called
+// with w.mu held.
+func (w *Writer) rotate() error {
+	if err := w.moveAside(w.path + "." + w.now().Format(timeFormat)); err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	old := w.file
+	if err := w.open(); err != nil {
+		return err
+	}
+	if err := old.Close(); err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	return w.prune()
+}
+
+// moveAside moves the log file to stamp, or to stamp.1, stamp.2 and so on
+// when that name is taken. Linking fails instead of replacing an existing
+// file, so a rotated file is never overwritten, even by another writer.
+// Filesystems without hard links fall back to a rename.
+func (w *Writer) moveAside(stamp string) error {
+	rotated := stamp
+	for n := 1; ; n++ {
+		err := os.Link(w.path, rotated)
+		if err == nil {
+			break
+		}
+		if os.IsNotExist(err) {
+			return nil // Removed behind our back: nothing to move
+		}
+		if !os.IsExist(err) {
+			if renameErr := os.Rename(w.path, rotated); renameErr != nil && !os.IsNotExist(renameErr) {
+				return renameErr
+			}
+			return nil
+		}
+		rotated = fmt.Sprintf("%s.%d", stamp, n)
+	}
+	if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// prune removes the oldest rotated files beyond MaxFiles
+func (w *Writer) prune() error {
+	matches, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return err
+	}
+	type rotatedFile struct {
+		name    string
+		stamp   string
+		counter int
+	}
+	var rotated []rotatedFile
+	for _, m := range matches {
+		if stamp, counter, ok := parseSuffix(strings.TrimPrefix(m, w.path+".")); ok {
+			rotated = append(rotated, rotatedFile{m, stamp, counter})
+		}
+	}
+	// Timestamps sort chronologically, so the newest come last
+	sort.Slice(rotated, func(i, j int) bool {
+		if rotated[i].stamp != rotated[j].stamp {
+			return rotated[i].stamp < rotated[j].stamp
+		}
+		return rotated[i].counter < rotated[j].counter
+	})
+	for len(rotated) > w.opts.MaxFiles {
+		if err := os.Remove(rotated[0].name); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("log file: %w", err)
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// parseSuffix splits the suffix of a rotated file into its timestamp and
+// counter, reporting false for files the writer did not rotate
+func parseSuffix(suffix string) (string, int, bool) {
+	if len(suffix) < len(timeFormat) {
+		return "", 0, false
+	}
+	stamp, rest := suffix[:len(timeFormat)], suffix[len(timeFormat):]
+	if _, err := time.Parse(timeFormat, stamp); err != nil {
+		return "", 0, false
+	}
+	if rest == "" {
+		return stamp, 0, true
+	}
+	counter, err := strconv.Atoi(strings.TrimPrefix(rest, "."))
+	if !strings.HasPrefix(rest, ".") || err != nil || counter < 1 {
+		return "", 0, false
+	}
+	return stamp, counter, true
+}
@@ -0,0 +1,145 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "bridge.log")
+	w, err := Open(path, Options{MaxSize: 10, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer w.Close()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "fourth\n" {
+		t.Errorf("Expected the current file to hold the last line, got %q", data)
+	}
+	rotated, _ := filepath.Glob(path + ".*")
+	if len(rotated) != 2 {
+		t.Fatalf("Expected 2 rotated files to be kept, got %v", rotated)
+	}
+	if data, _ := os.ReadFile(rotated[0]); string(data) != "second\n" {
+		t.Errorf("Expected the oldest file to be removed first, got %q in %s", data, rotated[0])
+	}
+}
+
+func TestWriterRotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bridge.log")
+	os.WriteFile(path, []byte("from yesterday\n"), 0o644)
+	stale := time.Now().Add(-25 * time.Hour)
+	os.Chtimes(path, stale, stale)
+
+	w, err := Open(path, Options{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer w.Close()
+	w.Write([]byte("today\n"))
+
+	if data, _ := os.ReadFile(path); string(data) != "today\n" {
+		t.Errorf("Expected a stale file to be rotated before writing, got %q", data)
+	}
+	rotated, _ := filepath.Glob(path + ".*")
+	if len(rotated) != 1 {
+		t.Fatalf("Expected one rotated file, got %v", rotated)
+	}
+	if data, _ := os.ReadFile(rotated[0]); !strings.Contains(string(data), "yesterday") {
+		t.Errorf("Expected the old content to be kept, got %q", data)
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("late\n")); err == nil {
+		t.Error("Expected writes after Close to fail")
+	}
+}
+
+func TestWriterRotatesWithinOneMillisecond(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bridge.log")
+	w, err := Open(path, Options{MaxSize: 5, MaxFiles: 3})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer w.Close()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	stamp := path + "." + now.Format(timeFormat)
+	for name, want := range map[string]string{stamp + ".1": "two\n", stamp + ".2": "three\n", stamp + ".3": "four\n"} {
+		if data, err := os.ReadFile(name); string(data) != want {
+			t.Errorf("Expected %q in %s, got %q (%v)", want, name, data, err)
+		}
+	}
+	if _, err := os.Stat(stamp); !os.IsNotExist(err) {
+		t.Errorf("Expected the oldest rotated file to be pruned, got %v", err)
+	}
+}
+
+func TestWriterKeepsFilesRotatedByOthers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bridge.log")
+	w, err := Open(path, Options{MaxSize: 5})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer w.Close()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+	stamp := path + "." + now.Format(timeFormat)
+	os.WriteFile(stamp, []byte("other\n"), 0o644)
+
+	w.Write([]byte("one\n"))
+	w.Write([]byte("two\n"))
+
+	if data, _ := os.ReadFile(stamp); string(data) != "other\n" {
+		t.Errorf("Expected a file rotated by another writer to be kept, got %q", data)
+	}
+	if data, _ := os.ReadFile(stamp + ".1"); string(data) != "one\n" {
+		t.Errorf("Expected the rotation to take the next free name, got %q", data)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "two\n" {
+		t.Errorf("Expected a fresh file after rotation, got %q", data)
+	}
+}
+
+func TestWriterRecoversFromFailedRotation(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "bridge.log")
+	w, err := Open(path, Options{MaxSize: 5})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer w.Close()
+	w.Write([]byte("first\n"))
+
+	// Without its directory the new file cannot be opened
+	os.RemoveAll(dir)
+	if n, err := w.Write([]byte("lost\n")); err == nil || n != 5 {
+		t.Errorf("Expected the write to go to the old file and report the rotation error, got %d, %v", n, err)
+	}
+
+	os.MkdirAll(dir, 0o755)
+	if _, err := w.Write([]byte("second\n")); err != nil {
+		t.Fatalf("Expected the next write to rotate again, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "second\n" {
+		t.Errorf("Expected logging to resume in a new file, got %q", data)
+	}
+}
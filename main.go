@@ -1,27 +1,160 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 
 	"mcp-bridge/internal/bridge"
+	"mcp-bridge/internal/config"
 )
 
 // version is set by the build process
 var version = "dev"
 
 var (
-	serverURL   = flag.String("server", "", "Remote MCP server URL (required)")
-	apiKey      = flag.String("key", "", "API key for authentication (required)")
+	configPath  = flag.String("config", "", "Configuration file (YAML or JSON)")
+	apiKey      = flag.String("key", "", "API key for authentication (required); comma-separate several to rotate on 401/429")
 	debug       = flag.Bool("debug", false, "Enable all debug logging (equivalent to -debug-client -debug-server)")
 	debugClient = flag.Bool("debug-client", false, "Enable client-side message logging")
 	debugServer = flag.Bool("debug-server", false, "Enable server-side message logging")
+	logLevel    = flag.String("log-level", "", "Minimum log level: debug, info, warn or error (default debug with a -debug flag, else info)")
+	logFormat   = flag.String("log-format", "text", "Log format: text or json")
+	logFile     = flag.String("log-file", "", "Write logs to this file instead of stderr, rotating it by size and age")
+	logMaxAge   = flag.Duration("log-max-age", 0, "Rotate the log file once it is this old, e.g. 24h (0 for never)")
+	logMaxFiles = flag.Int("log-max-files", 5, "Rotated log files to keep")
 	showVersion = flag.Bool("version", false, "Show version and exit")
+
+	transport      = flag.String("transport", "auto", "Remote transport: auto, streaming or post")
+	connectTimeout = flag.Duration("connect-timeout", 3*time.Second, "Timeout for the streaming transport probe")
+	requestTimeout = flag.Duration("request-timeout", 0, "Timeout for each HTTP POST request (0 for none)")
+	lazy           = flag.Bool("lazy", false, "Connect on first use, answering initialize from cached capabilities")
+	validateArgs   = flag.Bool("validate-args", false, "Check tools/call arguments against the tool's inputSchema before forwarding")
+	validateOutput = flag.String("validate-output", "", "Check structuredContent against the tool's outputSchema: log, annotate or error")
+	rateLimit      = flag.String("rate-limit", "", "Rate limit for all requests as count/period, e.g. 20/s")
+	rateLimitWait  = flag.Duration("rate-limit-wait", 0, "How long a rate-limited request may wait for its turn before being rejected")
+	truncate       = flag.Bool("truncate-responses", false, "Cut tool results over -max-response-size short instead of failing")
+	readOnly       = flag.Bool("read-only", false, "Only expose tools annotated readOnlyHint and block destructive ones")
+	approveWrites  = flag.Bool("approve-writes", false, "Require approval for tools not annotated with readOnlyHint")
+	approvalCmd    = flag.String("approval-command", "", "Approval hook command; receives each call as JSON on stdin")
+	approvalURL    = flag.String("approval-url", "", "Approval hook endpoint; receives each call as a JSON POST")
+	approvalWait   = flag.Duration("approval-timeout", 2*time.Minute, "How long to wait for an approval decision before denying")
+	healthCheck    = flag.Duration("health-check-interval", 30*time.Second, "How often to check whether a failed -server URL has recovered")
+
+	keyCooldown   = flag.Duration("key-cooldown", time.Minute, "How long a rejected API key is skipped before reuse")
+	tlsCert       = flag.String("tls-cert", "", "Client certificate (PEM) for mutual TLS")
+	tlsKey        = flag.String("tls-key", "", "Client private key (PEM) for mutual TLS")
+	tlsMinVersion = flag.String("tls-min-version", "", "Minimum TLS version (1.0, 1.1, 1.2, 1.3)")
+	tlsServerName = flag.String("tls-server-name", "", "Override the TLS server name (SNI)")
+	proxyURL      = flag.String("proxy", "", "Proxy URL (http, https, socks5, socks5h) or \"direct\"; overrides HTTP(S)_PROXY")
+	proxyUser     = flag.String("proxy-user", "", "Proxy credentials as user:password")
+	noProxy       = flag.String("no-proxy", "", "Comma-separated hosts, domains and CIDRs that bypass -proxy")
+	signMode      = flag.String("sign", "", "Sign requests: hmac or sigv4")
+	signSecret    = flag.String("sign-secret", "", "Shared secret for -sign hmac")
+	signHeader    = flag.String("sign-header", "", "Signature header for -sign hmac (default X-Signature)")
+	signCanonical = flag.String("sign-canonical", "", "What -sign hmac covers: body, timestamp-body or request")
+	sigv4Region   = flag.String("sigv4-region", "", "AWS region for -sign sigv4")
+	sigv4Service  = flag.String("sigv4-service", "execute-api", "AWS service for -sign sigv4")
+	awsProfile    = flag.String("aws-profile", "", "AWS credentials profile for -sign sigv4 (default: environment, then AWS_PROFILE)")
+	servers       repeatedList
+	headers       repeatedList
+	renameTools   repeatedList
+	redactPaths   stringList
+	maxRequest    config.ByteSize
+	methodLimits  repeatedList
+	readOnlyAllow stringList
+	toolLimits    repeatedList
+	maxResponse   config.ByteSize
+	logMaxSize    config.ByteSize
+	offload       config.ByteSize
+	offloadPage   config.ByteSize
+	redactRegexps repeatedList
+	failoverURLs  stringList
+	approveTools  stringList
+	reqFilters    repeatedList
+	respFilters   repeatedList
+	filterFlags   = map[string]*stringList{}
+	tlsCAFiles    stringList
+	tlsPins       stringList
 )
 
+func init() {
+	flag.Var(&servers, "server", "Remote MCP server URL (required); repeat as name=URL to aggregate several servers")
+	flag.Var(&failoverURLs, "failover", "Fallback URL used when -server fails; repeatable, tried in order")
+	flag.Var(&headers, "header", "Extra request header as Name: value; repeatable")
+	flag.Var(&readOnlyAllow, "read-only-allow", "Trust tools without annotations matching these glob patterns in -read-only mode; repeatable")
+	flag.Var(&approveTools, "approve-tools", "Require approval for tools matching these glob patterns; repeatable")
+	flag.Var(&renameTools, "rename-tool", "Expose a remote tool under another name as remote=exposed; other tool flags match the exposed name; repeatable")
+	for _, kind := range []string{"tools", "prompts", "resources"} {
+		allow, deny := &stringList{}, &stringList{}
+		filterFlags["allow-"+kind], filterFlags["deny-"+kind] = allow, deny
+		flag.Var(allow, "allow-"+kind, "Only expose "+kind+" matching these glob patterns; repeatable")
+		flag.Var(deny, "deny-"+kind, "Hide "+kind+" matching these glob patterns; repeatable")
+	}
+	flag.Var(&redactPaths, "redact-path", "Mask this JSON path in logged messages, e.g. params.arguments.password; repeatable")
+	flag.Var(&redactRegexps, "redact-pattern", "Mask matches of this regular expression in logs; repeatable")
+	flag.Var(&logMaxSize, "log-max-size", "Rotate the log file before it grows past this size (default 10MB)")
+	flag.Var(&maxRequest, "max-request-size", "Reject client messages larger than this, e.g. 1MB")
+	flag.Var(&maxResponse, "max-response-size", "Stop reading remote responses larger than this, e.g. 50MB")
+	flag.Var(&offload, "offload-threshold", "Keep tool results larger than this in the bridge and send a resource link, e.g. 100KB")
+	flag.Var(&offloadPage, "offload-page-size", "Page size for reading offloaded results (default: the threshold)")
+	flag.Var(&methodLimits, "rate-limit-method", "Rate limit a method as method=count/period, e.g. resources/read=60/min; repeatable")
+	flag.Var(&toolLimits, "rate-limit-tool", "Rate limit a tool as tool=count/period, e.g. search=10/min; repeatable")
+	flag.Var(&reqFilters, "transform-request", "Pipe client requests through this command, e.g. \"scrub-pii --strict\"; repeatable")
+	flag.Var(&respFilters, "transform-response", "Pipe responses through this command; repeatable")
+	flag.Var(&tlsCAFiles, "tls-ca", "Additional CA bundle (PEM) to trust; repeatable")
+	flag.Var(&tlsPins, "tls-pin", "Pinned server public key as sha256/<base64>; repeatable")
+}
+
+// stringList is a flag value that collects repeated or comma-separated values
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*s = append(*s, item)
+		}
+	}
+	return nil
+}
+
+// repeatedList is a flag value that collects repeated values as given
+type repeatedList []string
+
+func (s *repeatedList) String() string { return strings.Join(*s, " ") }
+
+func (s *repeatedList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// parseServerSpec splits "name=URL" into its parts. A bare URL has no name.
+func parseServerSpec(spec string) (name, url string) {
+	if name, url, ok := strings.Cut(spec, "="); ok && !strings.ContainsAny(name, ":/?") {
+		return name, url
+	}
+	return "", spec
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(os.Args[2:]); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
 	flag.Parse()
 
 	if *showVersion {
@@ -29,20 +162,400 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *serverURL == "" {
+	// Precedence: command line flags, then MCP_BRIDGE_* variables, then the
+	// config file, then built-in defaults
+	if err := config.ApplyEnv(flag.CommandLine, os.LookupEnv); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	cfg := &config.Config{}
+	if *configPath != "" {
+		var err error
+		if cfg, err = config.Load(*configPath); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	applyFlags(cfg)
+
+	if len(cfg.Servers) == 0 {
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	// Create bridge with debug settings
-	b := bridge.New(*serverURL, *apiKey, *debug)
+	out, err := cfg.Logging.Output()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	logger, err := cfg.Logging.Logger(out)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	slog.SetDefault(logger)
+	if out != os.Stderr {
+		defer out.Close()
+		logToFile = true
+	}
+
+	bridges := make([]*bridge.MCPBridge, len(cfg.Servers))
+	for i, server := range cfg.Servers {
+		b, err := server.Bridge(cfg.Logging)
+		if err != nil {
+			fatal(err)
+		}
+		b.Logger = logger
+		bridges[i] = b
+	}
 
-	// Set granular debug flags (debug flag enables both)
-	debugClientEnabled := *debug || *debugClient
-	debugServerEnabled := *debug || *debugServer
-	b.SetDebugFlags(debugClientEnabled, debugServerEnabled)
+	if len(bridges) == 1 {
+		err = bridges[0].Run()
+	} else {
+		logging := cfg.Logging
+		var agg *bridge.Aggregator
+		agg, err = bridge.NewAggregator(bridges, logging.Debug || logging.DebugClient || logging.DebugServer)
+		if err == nil {
+			agg.Logger = logger
+			err = agg.Run()
+		}
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
 
-	if err := b.Run(); err != nil {
-		log.Fatalf("Error: %v", err)
+// logToFile is set when logs go to -log-file rather than stderr
+var logToFile bool
+
+// fatal reports err and exits. Once slog is the default logger, log.Fatal
+// would log at info level, which -log-level may filter out. With a log file
+// the error also goes to stderr, where the IDE may show it.
+func fatal(err error) {
+	slog.Error(fmt.Sprintf("Error: %v", err))
+	if logToFile {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(1)
+}
+
+// applyFlags layers flags given on the command line (or through MCP_BRIDGE_*
+// variables) over the configuration. -server replaces the configured server
+// list; every other flag overrides its setting on each server.
+func applyFlags(cfg *config.Config) {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if set["server"] {
+		cfg.Servers = nil
+		for _, spec := range servers {
+			name, url := parseServerSpec(spec)
+			cfg.Servers = append(cfg.Servers, config.Server{Name: name, URL: url})
+		}
+	}
+
+	if set["debug"] {
+		cfg.Logging.Debug = *debug
+	}
+	if set["debug-client"] {
+		cfg.Logging.DebugClient = *debugClient
+	}
+	if set["debug-server"] {
+		cfg.Logging.DebugServer = *debugServer
+	}
+	if set["log-level"] {
+		cfg.Logging.Level = *logLevel
+	}
+	if set["log-format"] {
+		cfg.Logging.Format = *logFormat
+	}
+	if set["log-file"] {
+		cfg.Logging.File = *logFile
+	}
+	if set["log-max-size"] {
+		cfg.Logging.MaxSize = logMaxSize
+	}
+	if set["log-max-age"] {
+		cfg.Logging.MaxAge = *logMaxAge
+	}
+	if set["log-max-files"] {
+		cfg.Logging.MaxFiles = *logMaxFiles
+	}
+	if set["redact-path"] {
+		cfg.Logging.Redact.Paths = redactPaths
+	}
+	if set["redact-pattern"] {
+		cfg.Logging.Redact.Patterns = redactRegexps
+	}
+
+	for i := range cfg.Servers {
+		s := &cfg.Servers[i]
+		if set["key"] {
+			s.Auth.Keys = []string{*apiKey}
+		}
+		if set["key-cooldown"] {
+			s.Auth.KeyCooldown = *keyCooldown
+		}
+		if set["transport"] {
+			s.Transport = *transport
+		}
+		if set["connect-timeout"] {
+			s.Timeouts.Connect = *connectTimeout
+		}
+		if set["request-timeout"] {
+			s.Timeouts.Request = *requestTimeout
+		}
+		if set["lazy"] {
+			s.Lazy = *lazy
+		}
+		if set["failover"] {
+			s.Failover = failoverURLs
+		}
+		if set["health-check-interval"] {
+			s.Timeouts.HealthCheck = *healthCheck
+		}
+		if set["header"] {
+			if s.Headers == nil {
+				s.Headers = map[string]string{}
+			}
+			for _, h := range headers {
+				name, value, _ := strings.Cut(h, ":")
+				s.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+			}
+		}
+
+		for name, patterns := range filterFlags {
+			if !set[name] {
+				continue
+			}
+			mode, kind, _ := strings.Cut(name, "-")
+			filter := map[string]*config.Filter{"tools": &s.Tools.Filter, "prompts": &s.Prompts, "resources": &s.Resources}[kind]
+			if mode == "allow" {
+				filter.Allow = *patterns
+			} else {
+				filter.Deny = *patterns
+			}
+		}
+
+		if set["validate-args"] {
+			s.Tools.ValidateArguments = *validateArgs
+		}
+		if set["validate-output"] {
+			s.Tools.ValidateOutput = *validateOutput
+		}
+		if set["read-only"] {
+			s.Tools.ReadOnly = *readOnly
+		}
+		if set["read-only-allow"] {
+			s.Tools.ReadOnlyAllow = readOnlyAllow
+		}
+		approval := &s.Tools.Approval
+		if set["approve-tools"] {
+			approval.Tools = approveTools
+		}
+		if set["approve-writes"] {
+			approval.NotReadOnly = *approveWrites
+		}
+		if set["approval-command"] {
+			approval.Command = strings.Fields(*approvalCmd)
+		}
+		if set["approval-url"] {
+			approval.URL = *approvalURL
+		}
+		if set["approval-timeout"] {
+			approval.Timeout = *approvalWait
+		}
+		if set["transform-request"] || set["transform-response"] {
+			s.Transforms = nil
+			for _, command := range reqFilters {
+				s.Transforms = append(s.Transforms, config.Transform{Command: strings.Fields(command), Direction: bridge.TransformRequests})
+			}
+			for _, command := range respFilters {
+				s.Transforms = append(s.Transforms, config.Transform{Command: strings.Fields(command), Direction: bridge.TransformResponses})
+			}
+		}
+		if set["max-request-size"] {
+			s.Limits.MaxRequest = maxRequest
+		}
+		if set["max-response-size"] {
+			s.Limits.MaxResponse = maxResponse
+		}
+		if set["truncate-responses"] {
+			s.Limits.Truncate = *truncate
+		}
+		if set["offload-threshold"] {
+			s.Limits.Offload = offload
+		}
+		if set["offload-page-size"] {
+			s.Limits.OffloadPage = offloadPage
+		}
+		if set["rate-limit"] {
+			s.RateLimits.Rate = *rateLimit
+		}
+		if set["rate-limit-wait"] {
+			s.RateLimits.MaxWait = *rateLimitWait
+		}
+		for _, list := range []struct {
+			flag  string
+			specs repeatedList
+			dst   *map[string]config.RateLimit
+		}{{"rate-limit-method", methodLimits, &s.RateLimits.Methods}, {"rate-limit-tool", toolLimits, &s.RateLimits.Tools}} {
+			if !set[list.flag] {
+				continue
+			}
+			if *list.dst == nil {
+				*list.dst = map[string]config.RateLimit{}
+			}
+			for _, spec := range list.specs {
+				name, rate, _ := strings.Cut(spec, "=")
+				limit := (*list.dst)[name]
+				limit.Rate = rate
+				(*list.dst)[name] = limit
+			}
+		}
+		if set["rename-tool"] {
+			if s.Tools.Overrides == nil {
+				s.Tools.Overrides = map[string]config.ToolOverride{}
+			}
+			for _, spec := range renameTools {
+				remote, exposed, _ := strings.Cut(spec, "=")
+				o := s.Tools.Overrides[remote]
+				o.Name = exposed
+				s.Tools.Overrides[remote] = o
+			}
+		}
+
+		if set["tls-cert"] {
+			s.TLS.Cert = *tlsCert
+		}
+		if set["tls-key"] {
+			s.TLS.Key = *tlsKey
+		}
+		if set["tls-ca"] {
+			s.TLS.CA = tlsCAFiles
+		}
+		if set["tls-min-version"] {
+			s.TLS.MinVersion = *tlsMinVersion
+		}
+		if set["tls-server-name"] {
+			s.TLS.ServerName = *tlsServerName
+		}
+		if set["tls-pin"] {
+			s.TLS.Pins = tlsPins
+		}
+
+		if set["proxy"] {
+			s.Proxy.URL = *proxyURL
+		}
+		if set["proxy-user"] {
+			s.Proxy.Username, s.Proxy.Password, _ = strings.Cut(*proxyUser, ":")
+		}
+		if set["no-proxy"] {
+			s.Proxy.NoProxy = *noProxy
+		}
+
+		if s.Auth.Sign == nil {
+			s.Auth.Sign = &config.Sign{}
+		}
+		sign := s.Auth.Sign
+		if set["sign"] {
+			sign.Mode = *signMode
+		}
+		if set["sign-secret"] {
+			sign.Secret = *signSecret
+		}
+		if set["sign-header"] {
+			sign.Header = *signHeader
+		}
+		if set["sign-canonical"] {
+			sign.Canonical = *signCanonical
+		}
+		if set["sigv4-region"] {
+			sign.Region = *sigv4Region
+		}
+		if set["sigv4-service"] {
+			sign.Service = *sigv4Service
+		}
+		if set["aws-profile"] {
+			sign.Profile = *awsProfile
+		}
+	}
+}
+
+// runImport implements "mcp-bridge import": it converts the MCP server
+// entries of IDE configuration files into bridge configuration and can
+// rewrite those files so remote servers are launched through mcp-bridge
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	output := fs.String("o", "", "Write bridge configuration to this file instead of stdout")
+	rewrite := fs.Bool("rewrite", false, "Rewrite remote HTTP entries in place to launch mcp-bridge (keeps a .bak copy)")
+	command := fs.String("command", "mcp-bridge", "Command written into rewritten entries")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: mcp-bridge import [options] FILE...\n\n")
+		fmt.Fprintf(fs.Output(), "Reads Claude Desktop, Cursor, VS Code, Zed or Warp MCP configuration.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg := &config.Config{}
+	names := map[string]string{}
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		imported, skipped, err := config.ImportIDE(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, name := range skipped {
+			log.Printf("%s: skipping %s (not a remote HTTP server)", path, name)
+		}
+		for _, server := range imported.Servers {
+			if prev, ok := names[server.Name]; ok {
+				log.Printf("%s: skipping %s (already imported from %s)", path, server.Name, prev)
+				continue
+			}
+			names[server.Name] = path
+			cfg.Servers = append(cfg.Servers, server)
+		}
+
+		if *rewrite {
+			out, rewritten, err := config.RewriteIDE(data, *command)
+			if errors.Is(err, config.ErrIDEComments) {
+				log.Printf("%s: not rewriting: %v", path, err)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if len(rewritten) == 0 {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(path+".bak", data, info.Mode().Perm()); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+				return err
+			}
+			log.Printf("%s: rewrote %s (backup in %s)", path, strings.Join(rewritten, ", "), filepath.Base(path)+".bak")
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
 	}
+	return os.WriteFile(*output, buf.Bytes(), 0o600)
 }